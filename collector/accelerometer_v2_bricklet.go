@@ -0,0 +1,165 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/accelerometer_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(accelerometerV2Bricklet{})
+}
+
+// standardGravity is 1gₙ in m/s²
+const standardGravity = 9.80665
+
+var (
+	// accelerometerV2Acceleration is sent for each axis, see axes
+	accelerometerV2Acceleration = ValueDesc{
+		Index:  0,
+		Name:   "acceleration",
+		Help:   "Acceleration including gravity in m/s²",
+		Type:   prometheus.GaugeValue,
+		Unit:   "m/s²",
+		Scale:  standardGravity / 10000,
+		Labels: []string{"axis"},
+	}
+	accelerometerV2Vibration = vibrationValues(3)
+)
+
+// accelerometerV2FullScales maps the `full_scale` config values to the full scale of the
+// bricklet
+var accelerometerV2FullScales = map[int]uint8{
+	2: accelerometer_v2_bricklet.FullScale2g,
+	4: accelerometer_v2_bricklet.FullScale4g,
+	8: accelerometer_v2_bricklet.FullScale8g,
+}
+
+// accelerometerV2DataRates are the data rates of the bricklet used for the vibration summary,
+// the continuous mode delivers at most 10000 Hz with 16 bit samples of all axes, so 12800 and
+// 25600 Hz are not used
+var accelerometerV2DataRates = []struct {
+	hz   int
+	rate uint8
+}{
+	{25, accelerometer_v2_bricklet.DataRate25Hz},
+	{50, accelerometer_v2_bricklet.DataRate50Hz},
+	{100, accelerometer_v2_bricklet.DataRate100Hz},
+	{200, accelerometer_v2_bricklet.DataRate200Hz},
+	{400, accelerometer_v2_bricklet.DataRate400Hz},
+	{800, accelerometer_v2_bricklet.DataRate800Hz},
+	{1600, accelerometer_v2_bricklet.DataRate1600Hz},
+	{3200, accelerometer_v2_bricklet.DataRate3200Hz},
+	{6400, accelerometer_v2_bricklet.DataRate6400Hz},
+}
+
+// accelerometerV2MaxSampleRate is the highest sample_rate of an Accelerometer Bricklet 2.0
+const accelerometerV2MaxSampleRate = 6400
+
+// accelerometerV2DataRate returns the lowest data rate of the bricklet of at least the sample
+// rate, 800Hz for 0
+func accelerometerV2DataRate(sampleRate int) (hz int, rate uint8) {
+	if sampleRate == 0 {
+		sampleRate = 800
+	}
+	for _, r := range accelerometerV2DataRates {
+		hz, rate = r.hz, r.rate
+		if r.hz >= sampleRate {
+			break
+		}
+	}
+	return hz, rate
+}
+
+type accelerometerV2Bricklet struct{}
+
+func (accelerometerV2Bricklet) DeviceIdentifier() uint16 {
+	return accelerometer_v2_bricklet.DeviceIdentifier
+}
+func (accelerometerV2Bricklet) Name() string { return "accelerometer_bricklet_v2" }
+
+func (accelerometerV2Bricklet) Values() []ValueDesc {
+	return append([]ValueDesc{accelerometerV2Acceleration}, accelerometerV2Vibration...)
+}
+
+func (accelerometerV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := accelerometer_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Accelerometer Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	b.RLock()
+	s, ok := b.Vibration[dev.UID]
+	b.RUnlock()
+	if !ok {
+		if err := d.SetContinuousAccelerationConfiguration(false, false, false, accelerometer_v2_bricklet.Resolution16bit); err != nil {
+			log.Errorf("failed to disable continuous acceleration (uid=%s): %s", dev.UID, err)
+		}
+		accID := d.RegisterAccelerationCallback(func(x, y, z int32) {
+			for i, acc := range []int32{x, y, z} {
+				desc := accelerometerV2Acceleration
+				desc.Index += i
+				b.SendLabels(dev, 0, desc, float64(acc), map[string]string{"axis": axes[i]})
+			}
+		})
+		if err := d.SetAccelerationCallbackConfiguration(b.CallbackPeriod, false); err != nil {
+			log.Errorf("failed to set acceleration callback of Accelerometer Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+		}
+		return []Register{
+			{
+				Deregister: d.DeregisterAccelerationCallback,
+				ID:         accID,
+			},
+		}, nil
+	}
+
+	fullScale, ok := accelerometerV2FullScales[s.FullScale]
+	if !ok {
+		if _, fullScale, err = d.GetConfiguration(); err != nil {
+			return nil, fmt.Errorf("failed to get configuration of Accelerometer Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+		}
+	}
+	hz, rate := accelerometerV2DataRate(s.SampleRate)
+	if err := d.SetConfiguration(rate, fullScale); err != nil {
+		log.Errorf("failed to configure Accelerometer Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+	log.Debugf("sampling Accelerometer Bricklet 2.0 (uid=%s) at %d Hz", dev.UID, hz)
+
+	// the 16 bit samples are converted to gₙ/10000 with 625 / 1024 at 2gₙ full scale, twice
+	// that at 4gₙ and so on
+	factor := 625.0 / 1024 * float64(int(1)<<fullScale) * standardGravity / 10000
+	var v vibration
+	samplesID := d.RegisterContinuousAcceleration16BitCallback(func(samples [30]int16) {
+		acc := make([][3]float64, 0, len(samples)/3)
+		for i := 0; i+2 < len(samples); i += 3 {
+			acc = append(acc, [3]float64{float64(samples[i]) * factor, float64(samples[i+1]) * factor, float64(samples[i+2]) * factor})
+		}
+		v.add(acc...)
+	})
+	if err := d.SetContinuousAccelerationConfiguration(true, true, true, accelerometer_v2_bricklet.Resolution16bit); err != nil {
+		log.Errorf("failed to enable continuous acceleration (uid=%s): %s", dev.UID, err)
+	}
+
+	sendVibration := b.sendVibration(dev, &v, accelerometerV2Vibration, func(summary [3]vibrationSummary) {
+		// the acceleration callback is disabled by the continuous callback, the mean of the
+		// samples is sent instead
+		for i := range summary {
+			desc := accelerometerV2Acceleration
+			desc.Index += i
+			desc.Scale = 0
+			b.SendLabels(dev, 0, desc, summary[i].mean, map[string]string{"axis": axes[i]})
+		}
+	})
+
+	return []Register{
+		{
+			Deregister: d.DeregisterContinuousAcceleration16BitCallback,
+			ID:         samplesID,
+		},
+		sendVibration,
+	}, nil
+}
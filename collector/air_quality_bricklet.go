@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/air_quality_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(airQualityBricklet{})
+}
+
+var (
+	airQualityIAQIndex = ValueDesc{
+		Index:       0,
+		Name:        "iaq_index",
+		Help:        "IAQ Index Value",
+		Type:        prometheus.GaugeValue,
+		HAType:      "sensor",
+		DeviceClass: "aqi",
+	}
+	airQualityIAQIndexAccuracy = ValueDesc{
+		Index: 1,
+		Name:  "iaq_index_accuracy",
+		Help:  "IAQ Index Accuracy",
+		Type:  prometheus.GaugeValue,
+	}
+	airQualityTemperature = ValueDesc{
+		Index:       2,
+		Name:        "temperature",
+		Help:        "Temperature in °C",
+		Type:        prometheus.GaugeValue,
+		Unit:        "°C",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "temperature",
+	}
+	airQualityPressure = ValueDesc{
+		Index:       3,
+		Name:        "pressure",
+		Help:        "Air Pressure in hPa",
+		Type:        prometheus.GaugeValue,
+		Unit:        "hPa",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "atmospheric_pressure",
+	}
+	airQualityHumidity = ValueDesc{
+		Index:       4,
+		Name:        "humidity",
+		Help:        "Humidity of the air in %rH",
+		Type:        prometheus.GaugeValue,
+		Unit:        "%",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "humidity",
+	}
+)
+
+type airQualityBricklet struct{}
+
+func (airQualityBricklet) DeviceIdentifier() uint16 { return air_quality_bricklet.DeviceIdentifier }
+func (airQualityBricklet) Name() string             { return "air_quality_bricklet" }
+
+// HAUniqueID keeps the unique IDs of the entities created by older versions
+func (airQualityBricklet) HAUniqueID(uid string) string { return "air_quality_bricklet" + uid }
+
+func (airQualityBricklet) Values() []ValueDesc {
+	return []ValueDesc{
+		airQualityIAQIndex,
+		airQualityIAQIndexAccuracy,
+		airQualityTemperature,
+		airQualityPressure,
+		airQualityHumidity,
+	}
+}
+
+func (airQualityBricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := air_quality_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Air Quality Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	cbID := d.RegisterAllValuesCallback(func(iaqIndex int32, iaqIndexAccuracy uint8, temperature int32, humidity int32, airPressure int32) {
+		b.Send(dev, 0, airQualityIAQIndex, float64(iaqIndex))
+		b.Send(dev, 0, airQualityIAQIndexAccuracy, float64(iaqIndexAccuracy))
+		b.Send(dev, 0, airQualityTemperature, float64(temperature))
+		b.Send(dev, 0, airQualityPressure, float64(airPressure))
+		b.Send(dev, 0, airQualityHumidity, float64(humidity))
+	})
+	if err := d.SetAllValuesCallbackConfiguration(b.CallbackPeriod, false); err != nil {
+		return nil, fmt.Errorf("failed to set callback config for Air Quality Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterAllValuesCallback,
+			ID:         cbID,
+		},
+	}, nil
+}
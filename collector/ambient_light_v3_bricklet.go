@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/ambient_light_v3_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(ambientLightV3Bricklet{})
+}
+
+var ambientLightV3Illuminance = ValueDesc{
+	Index:       0,
+	Name:        "illuminance",
+	Help:        "Illuminance in Lux",
+	Type:        prometheus.GaugeValue,
+	Unit:        "lx",
+	Scale:       0.01,
+	HAType:      "sensor",
+	DeviceClass: "illuminance",
+}
+
+type ambientLightV3Bricklet struct{}
+
+func (ambientLightV3Bricklet) DeviceIdentifier() uint16 {
+	return ambient_light_v3_bricklet.DeviceIdentifier
+}
+func (ambientLightV3Bricklet) Name() string        { return "ambient_light_v3_bricklet" }
+func (ambientLightV3Bricklet) Values() []ValueDesc { return []ValueDesc{ambientLightV3Illuminance} }
+
+func (ambientLightV3Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := ambient_light_v3_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Ambient Light V3.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	ilID := d.RegisterIlluminanceCallback(func(illuminance uint32) {
+		b.Send(dev, 0, ambientLightV3Illuminance, float64(illuminance))
+	})
+	d.SetIlluminanceCallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterIlluminanceCallback,
+			ID:         ilID,
+		},
+	}, nil
+}
@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/analog_in_v3_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(analogInV3Bricklet{})
+}
+
+var analogInV3Voltage = ValueDesc{
+	Index:       0,
+	Name:        "voltage",
+	Help:        "Voltage in V",
+	Type:        prometheus.GaugeValue,
+	Unit:        "V",
+	Scale:       0.001,
+	HAType:      "sensor",
+	DeviceClass: "voltage",
+}
+
+type analogInV3Bricklet struct{}
+
+func (analogInV3Bricklet) DeviceIdentifier() uint16 { return analog_in_v3_bricklet.DeviceIdentifier }
+func (analogInV3Bricklet) Name() string             { return "analog_in_v3_bricklet" }
+func (analogInV3Bricklet) Values() []ValueDesc      { return []ValueDesc{analogInV3Voltage} }
+
+func (analogInV3Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := analog_in_v3_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect AnalogInV3 Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	callbackID := d.RegisterVoltageCallback(func(voltage uint16) {
+		b.Send(dev, 0, analogInV3Voltage, float64(voltage))
+	})
+
+	// set period to b.CallbackPeriod
+	// valueHasToChange to false to also collect metrics if voltage is stable
+	// Threshold is turned off and min/max zero to always collect metrics in fixed period
+	d.SetVoltageCallbackConfiguration(b.CallbackPeriod, false, 'x', 0, 0)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterVoltageCallback,
+			ID:         callbackID,
+		},
+	}, nil
+}
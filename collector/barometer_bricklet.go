@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/barometer_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(barometerBricklet{})
+}
+
+var (
+	barometerAirPressure = ValueDesc{
+		Index:       0,
+		Name:        "air_pressure",
+		Help:        "Air Pressure in hPa",
+		Type:        prometheus.GaugeValue,
+		Unit:        "hPa",
+		Scale:       0.001,
+		HAType:      "sensor",
+		DeviceClass: "atmospheric_pressure",
+	}
+	barometerAltitude = ValueDesc{
+		Index:       1,
+		Name:        "altitude",
+		Help:        "Altitude in m",
+		Type:        prometheus.GaugeValue,
+		Unit:        "m",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "distance",
+	}
+)
+
+type barometerBricklet struct{}
+
+func (barometerBricklet) DeviceIdentifier() uint16 { return barometer_bricklet.DeviceIdentifier }
+func (barometerBricklet) Name() string             { return "barometer_bricklet" }
+
+func (barometerBricklet) Values() []ValueDesc {
+	return []ValueDesc{barometerAirPressure, barometerAltitude}
+}
+
+func (barometerBricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := barometer_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Barometer Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	apID := d.RegisterAirPressureCallback(func(airPressure int32) {
+		b.Send(dev, 0, barometerAirPressure, float64(airPressure))
+	})
+	d.SetAirPressureCallbackPeriod(b.CallbackPeriod)
+
+	altID := d.RegisterAltitudeCallback(func(altitude int32) {
+		b.Send(dev, 0, barometerAltitude, float64(altitude))
+	})
+	d.SetAltitudeCallbackPeriod(b.CallbackPeriod)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterAirPressureCallback,
+			ID:         apID,
+		},
+		{
+			Deregister: d.DeregisterAltitudeCallback,
+			ID:         altID,
+		},
+	}, nil
+}
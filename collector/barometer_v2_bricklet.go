@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/barometer_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(barometerV2Bricklet{})
+}
+
+var (
+	barometerV2AirPressure = ValueDesc{
+		Index:       0,
+		Name:        "air_pressure",
+		Help:        "Air Pressure in hPa",
+		Type:        prometheus.GaugeValue,
+		Unit:        "hPa",
+		Scale:       0.001,
+		HAType:      "sensor",
+		DeviceClass: "atmospheric_pressure",
+	}
+	barometerV2Altitude = ValueDesc{
+		Index:       1,
+		Name:        "altitude",
+		Help:        "Altitude in m",
+		Type:        prometheus.GaugeValue,
+		Unit:        "m",
+		Scale:       0.001,
+		HAType:      "sensor",
+		DeviceClass: "distance",
+	}
+	barometerV2Temperature = ValueDesc{
+		Index:       2,
+		Name:        "bricklet_temperature",
+		Help:        "Temperature of the bricklet in °C",
+		Type:        prometheus.GaugeValue,
+		Unit:        "°C",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "temperature",
+	}
+)
+
+type barometerV2Bricklet struct{}
+
+func (barometerV2Bricklet) DeviceIdentifier() uint16 { return barometer_v2_bricklet.DeviceIdentifier }
+func (barometerV2Bricklet) Name() string             { return "barometer_bricklet_v2" }
+
+func (barometerV2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{barometerV2AirPressure, barometerV2Altitude, barometerV2Temperature}
+}
+
+func (barometerV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := barometer_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Barometer Bricklet V2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	apID := d.RegisterAirPressureCallback(func(airPressure int32) {
+		b.Send(dev, 0, barometerV2AirPressure, float64(airPressure))
+	})
+	d.SetAirPressureCallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	altID := d.RegisterAltitudeCallback(func(altitude int32) {
+		b.Send(dev, 0, barometerV2Altitude, float64(altitude))
+	})
+	d.SetAltitudeCallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	tempID := d.RegisterTemperatureCallback(func(temperature int32) {
+		b.Send(dev, 0, barometerV2Temperature, float64(temperature))
+	})
+	d.SetTemperatureCallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterAirPressureCallback,
+			ID:         apID,
+		},
+		{
+			Deregister: d.DeregisterAltitudeCallback,
+			ID:         altID,
+		},
+		{
+			Deregister: d.DeregisterTemperatureCallback,
+			ID:         tempID,
+		},
+	}, nil
+}
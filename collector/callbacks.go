@@ -2,6 +2,7 @@ package collector
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/Tinkerforge/go-api-bindings/ipconnection"
 	log "github.com/sirupsen/logrus"
@@ -22,7 +23,10 @@ func (b *BrickdCollector) OnConnect(reason ipconnection.DisconnectReason) {
 	if b.Password != "" {
 		err := b.Connection.Authenticate(b.Password)
 		if err != nil {
-			log.Errorf("Could not authenticate:", err)
+			log.Errorf("Could not authenticate: %s", err)
+			b.Lock()
+			b.AuthFailureCounter += 1
+			b.Unlock()
 			return
 		}
 		log.Debugf("Authentication succeded")
@@ -31,6 +35,7 @@ func (b *BrickdCollector) OnConnect(reason ipconnection.DisconnectReason) {
 
 	b.Lock()
 	b.ConnectCounter += 1
+	b.LastConnect = time.Now()
 	b.Unlock()
 
 	b.Connection.Enumerate() // call now, so we get devices when we initially connect
@@ -50,20 +55,28 @@ func (b *BrickdCollector) OnDisconnect(reason ipconnection.DisconnectReason) {
 	log.Infof("disconnected from brickd: %s", why)
 
 	b.Lock()
-	for _, dev := range b.Data.Devices {
-		if reg, ok := b.Registry[dev.UID]; ok {
-			for _, d := range reg {
-				log.Debugf("deregistering callback %d of %s", d.ID, dev.UID)
-				d.Deregister(d.ID)
-			}
-			delete(b.Registry, dev.UID)
-			delete(b.Data.Values, dev.UID)
-		}
+	b.DisconnectCounter[disconnectReasons[reason]] += 1
+	b.session++
+	for uid := range b.Data.Devices {
+		b.removeDevice(uid)
 	}
 	b.Data.Devices = make(map[string]*Device)
 	b.Unlock()
 }
 
+// removeDevice deregisters all callbacks of the device and forgets it, b must be locked
+func (b *BrickdCollector) removeDevice(uid string) {
+	if reg, ok := b.Registry[uid]; ok {
+		for _, d := range reg {
+			log.Debugf("deregistering callback %d of %s", d.ID, uid)
+			d.Deregister(d.ID)
+		}
+		delete(b.Registry, uid)
+		delete(b.Data.Values, uid)
+	}
+	delete(b.Data.Devices, uid)
+}
+
 // OnEnumerate receives the callbacks from the Enumerate() call
 func (b *BrickdCollector) OnEnumerate(
 	uid string,
@@ -74,9 +87,6 @@ func (b *BrickdCollector) OnEnumerate(
 	deviceIdentifier uint16,
 	enumerationType ipconnection.EnumerationType) {
 
-	if b.ignored(uid) {
-		return
-	}
 	dev := &Device{
 		UID:             uid,
 		ConnectedUID:    connectedUid,
@@ -84,6 +94,7 @@ func (b *BrickdCollector) OnEnumerate(
 		HardwareVersion: fmt.Sprintf("%d.%d.%d", hardwareVersion[0], hardwareVersion[1], hardwareVersion[2]),
 		FirmwareVersion: fmt.Sprintf("%d.%d.%d", firmwareVersion[0], firmwareVersion[1], firmwareVersion[2]),
 		DeviceID:        deviceIdentifier,
+		LastEnumerated:  time.Now(),
 	}
 	if enumerationType == ipconnection.EnumerationTypeAvailable {
 		dev.Available = true
@@ -92,21 +103,62 @@ func (b *BrickdCollector) OnEnumerate(
 	b.Lock()
 	defer b.Unlock()
 
-	regFunc, ok := b.Devices[dev.DeviceID]
+	if b.ignored(uid) {
+		return
+	}
+
+	if enumerationType == ipconnection.EnumerationTypeDisconnected {
+		log.Debugf("device disconnected (uid=%s)", dev.UID)
+		b.removeDevice(dev.UID)
+		return
+	}
+
+	drv, ok := b.Drivers[dev.DeviceID]
 	if !ok {
-		log.Debugf("no callbacks available for %s (uid=%s)", DeviceName(dev.DeviceID), dev.UID)
+		if _, known := b.Data.Devices[dev.UID]; !known {
+			log.Infof("no callbacks available for %s (uid=%s, device id=%d)", DeviceName(dev.DeviceID), dev.UID, dev.DeviceID)
+		}
+		b.Data.Devices[dev.UID] = dev
 		return
 	}
+	dev.Supported = true
 
 	if _, ok := b.Registry[dev.UID]; ok {
 		log.Debugf("callback already registered for %s (uid=%s)", DeviceName(dev.DeviceID), dev.UID)
+		if known, ok := b.Data.Devices[dev.UID]; ok {
+			// set by the driver when registering
+			dev.statusLED = known.statusLED
+			dev.scale = known.scale
+		}
+		b.Data.Devices[dev.UID] = dev
 		for _, reg := range b.Registry[dev.UID] {
 			log.Debugf("callback for %s (uid=%s): %d", DeviceName(dev.DeviceID), dev.UID, reg.ID)
 		}
 		return
 	}
 
-	reg, err := regFunc(dev)
+	if b.registering[dev.UID] {
+		log.Debugf("registration of %s (uid=%s) in progress", DeviceName(dev.DeviceID), dev.UID)
+		return
+	}
+	b.registering[dev.UID] = true
+	session := b.session
+
+	// the lock must not be held while registering: registering callbacks waits for the callback
+	// goroutine of the connection, which may itself wait for the lock in OnDisconnect or while
+	// sending a value
+	b.Unlock()
+	reg, err := drv.Register(b, dev)
+	if _, ok := drv.(MultiSensorDriver); !ok && err == nil {
+		values := drv.Values()
+		if hv, ok := drv.(haValuer); ok {
+			values = hv.HAValues(b, dev)
+		}
+		b.PublishHAConfig(drv, dev, 0, values)
+	}
+	b.Lock()
+
+	delete(b.registering, dev.UID)
 	if err != nil {
 		log.Warnf("failed to register device %s (uid=%s): %s", DeviceName(dev.DeviceID), dev.UID, err)
 		return
@@ -115,6 +167,13 @@ func (b *BrickdCollector) OnEnumerate(
 		log.Debugf("no registry returned from %s (uid=%s)", DeviceName(dev.DeviceID), dev.UID)
 		return
 	}
+	if session != b.session {
+		log.Debugf("disconnected while registering %s (uid=%s)", DeviceName(dev.DeviceID), dev.UID)
+		for _, d := range reg {
+			d.Deregister(d.ID)
+		}
+		return
+	}
 	b.Data.Devices[dev.UID] = dev
 	b.Registry[dev.UID] = reg
 	for _, reg := range b.Registry[dev.UID] {
@@ -0,0 +1,92 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/co2_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(co2V2Bricklet{})
+}
+
+var (
+	co2V2CO2Concentration = ValueDesc{
+		Index:       0,
+		Name:        "co2_concentration",
+		Help:        "CO2 Concentration in PPM",
+		Type:        prometheus.GaugeValue,
+		Unit:        "ppm",
+		HAType:      "sensor",
+		DeviceClass: "carbon_dioxide",
+	}
+	co2V2Humidity = ValueDesc{
+		Index:       1,
+		Name:        "humidity",
+		Help:        "Humidity of the air in %rH",
+		Type:        prometheus.GaugeValue,
+		Unit:        "%",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "humidity",
+	}
+	co2V2Temperature = ValueDesc{
+		Index:       2,
+		Name:        "temperature",
+		Help:        "Temperature in °C",
+		Type:        prometheus.GaugeValue,
+		Unit:        "°C",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "temperature",
+	}
+)
+
+type co2V2Bricklet struct{}
+
+func (co2V2Bricklet) DeviceIdentifier() uint16 { return co2_v2_bricklet.DeviceIdentifier }
+func (co2V2Bricklet) Name() string             { return "co2_v2_bricklet" }
+
+func (co2V2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{co2V2CO2Concentration, co2V2Humidity, co2V2Temperature}
+}
+
+func (co2V2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := co2_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect CO2 Bricklet V2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	coID := d.RegisterCO2ConcentrationCallback(func(co2Concentration uint16) {
+		b.Send(dev, 0, co2V2CO2Concentration, float64(co2Concentration))
+	})
+	d.SetCO2ConcentrationCallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	huID := d.RegisterHumidityCallback(func(humidity uint16) {
+		b.Send(dev, 0, co2V2Humidity, float64(humidity))
+	})
+	d.SetHumidityCallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	tempID := d.RegisterTemperatureCallback(func(temperature int16) {
+		b.Send(dev, 0, co2V2Temperature, float64(temperature))
+	})
+	d.SetTemperatureCallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterCO2ConcentrationCallback,
+			ID:         coID,
+		},
+		{
+			Deregister: d.DeregisterHumidityCallback,
+			ID:         huID,
+		},
+		{
+			Deregister: d.DeregisterTemperatureCallback,
+			ID:         tempID,
+		},
+	}, nil
+}
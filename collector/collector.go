@@ -1,36 +1,16 @@
 package collector
 
 import (
-	"math"
+	"reflect"
 	"strconv"
 	"sync"
 	"time"
 
-	"github.com/Tinkerforge/go-api-bindings/air_quality_bricklet"
-	"github.com/Tinkerforge/go-api-bindings/analog_in_v3_bricklet"
-	"github.com/Tinkerforge/go-api-bindings/hat_brick"
 	"github.com/Tinkerforge/go-api-bindings/ipconnection"
+	"github.com/Tinkerforge/go-api-bindings/sound_pressure_level_bricklet"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	"github.com/vetinari/brickd_exporter/mqtt"
-
-	// bricks:
-	"github.com/Tinkerforge/go-api-bindings/hat_zero_brick"
-	"github.com/Tinkerforge/go-api-bindings/master_brick"
-
-	// bricklets:
-	"github.com/Tinkerforge/go-api-bindings/ambient_light_v3_bricklet"
-	"github.com/Tinkerforge/go-api-bindings/barometer_bricklet"
-	"github.com/Tinkerforge/go-api-bindings/barometer_v2_bricklet"
-	"github.com/Tinkerforge/go-api-bindings/co2_v2_bricklet"
-	"github.com/Tinkerforge/go-api-bindings/humidity_bricklet"
-	"github.com/Tinkerforge/go-api-bindings/humidity_v2_bricklet"
-	"github.com/Tinkerforge/go-api-bindings/outdoor_weather_bricklet"
-	"github.com/Tinkerforge/go-api-bindings/uv_light_v2_bricklet"
-)
-
-const (
-	EthernetCallbackID uint64 = math.MaxUint64
 )
 
 var Version string
@@ -42,41 +22,107 @@ type BrickdCollector struct {
 	Password       string
 	Data           *BrickData
 	Registry       map[string][]Register
+	registering    map[string]bool // UIDs of devices being registered
+	session        uint64          // incremented on disconnect, registrations of an older session are dropped
 	Connection     ipconnection.IPConnection
 	Values         chan Value
-	Devices        map[uint16]RegisterFunc
+	Drivers        map[uint16]Driver
 	CallbackPeriod uint32
 	IgnoredUIDs    []string
 	Labels         map[string]string
 	SensorLabels   map[string]map[string]map[string]string
-	EthernetState  chan interface{}
 	ExpirePeriod   time.Duration
 	ConnectCounter int64
 	MQTT           *mqtt.MQTT
 	LEDStatus      string
+
+	DeviceLEDStatus map[string]string // LED status by UID, overrides LEDStatus
+	ledOverrides    map[string]string // LED status changed at runtime by UID
+
+	DisconnectCounter  map[string]int64 // by reason, see disconnectReasons
+	AuthFailureCounter int64
+	LastConnect        time.Time
+
+	ReceivedTimestamps bool // export brickd_value_last_received_timestamp_seconds for each value
+	ValueTimestamps    bool // export the values with the time they were received
+
+	OutdoorWeatherMaxAge time.Duration // remove outdoor weather sensors not seen for this long, 0 to keep them
+
+	SoundPressureLevel SoundPressureLevelSettings
+	Tanks              map[string]TankSettings               // tanks measured by distance bricklets, by UID
+	Counters           map[string]map[string]CounterSettings // Industrial Counter Bricklet channels by UID and channel
+	VoltageCurrent     map[string]VoltageCurrentSettings     // Voltage/Current Bricklet 2.0 settings by UID
+	LoadCells          map[string]LoadCellSettings           // Load Cell Bricklet 2.0 settings by UID
+	Vibration          map[string]VibrationSettings          // IMUs and accelerometers with vibration summary by UID
+	tareOverrides      map[string]float64                    // tare set at runtime by UID, see Tare
+	saveTare           func(uid string, tare float64) error  // saves a tare set at runtime, see SetTareSaver
+	eventCounts        map[string]map[int64]float64          // event counters by UID and index, not expired, see SendEvent
+
+	done      chan struct{} // closed by Close
+	closeOnce sync.Once
+	mqttStop  chan struct{} // stops the running ExportMQTT
+
+	haRepeaters map[string]chan struct{}        // stops republishing the HA config, by config topic
+	commands    map[string]func(payload []byte) // MQTT command handlers by topic, see Subscribe
+	subMu       sync.Mutex                      // changes one subscription at a time, see syncSubscription
+}
+
+// Settings are the settings of a BrickdCollector which can be changed without reconnecting
+// to brickd, see Reload
+type Settings struct {
+	IgnoredUIDs        []string
+	Labels             map[string]string
+	SensorLabels       map[string]map[string]map[string]string
+	ExpirePeriod       time.Duration
+	LEDStatus          string
+	DeviceLEDStatus    map[string]string
+	ReceivedTimestamps bool
+	ValueTimestamps    bool
+
+	OutdoorWeatherMaxAge time.Duration
+
+	SoundPressureLevel SoundPressureLevelSettings
+	Tanks              map[string]TankSettings
+	Counters           map[string]map[string]CounterSettings
+	VoltageCurrent     map[string]VoltageCurrentSettings
+	LoadCells          map[string]LoadCellSettings
+	Vibration          map[string]VibrationSettings
 }
 
-// RegisterFunc is the funcion of BrickdCollector to register callbacks
-type RegisterFunc func(*Device) ([]Register, error)
+// disconnectReasons are the values of the "reason" label of brickd_disconnects_total
+var disconnectReasons = map[ipconnection.DisconnectReason]string{
+	ipconnection.DisconnectReasonRequest:  "request",
+	ipconnection.DisconnectReasonError:    "error",
+	ipconnection.DisconnectReasonShutdown: "shutdown",
+}
+
+// connectionStates are the values of the "state" label of brickd_connection_state
+var connectionStates = map[ipconnection.ConnectionState]string{
+	ipconnection.ConnectionStateDisconnected: "disconnected",
+	ipconnection.ConnectionStateConnected:    "connected",
+	ipconnection.ConnectionStatePending:      "pending",
+}
 
 // BrickData are discovered devices and their values
 type BrickData struct {
 	Address string
 	Devices map[string]*Device
-	Values  map[string]map[int]Value
+	Values  map[string]map[int64]Value
 }
 
 // Value is returned from the callbacks
 type Value struct {
-	Index    int                  // index in BrickData.Values, needs to be assigned by the callback
-	DeviceID uint16               // https://www.tinkerforge.com/en/doc/Software/Device_Identifier.html
-	UID      string               // UID as given from brickd
-	SensorID int                  // sensor id in outdoor_weather_bricklet
-	Type     prometheus.ValueType // probably just prometheus.GaugeValue
-	Help     string               // help for users, i.e. prometheus' "# HELP brickd_humidity_value ..." line, (just the help text)
-	Name     string               // value name, such as "usb_voltage" or "humidity"
-	Value    float64              // the measurement value
-	Received time.Time            // when the value was received
+	Index    int64             // index in BrickData.Values, needs to be assigned by the callback
+	DeviceID uint16            // https://www.tinkerforge.com/en/doc/Software/Device_Identifier.html
+	UID      string            // UID as given from brickd
+	SensorID int64             // sensor id in outdoor_weather_bricklet
+	Name     string            // value name, such as "usb_voltage" or "humidity", see ValueDesc
+	Value    float64           // the measurement value
+	Received time.Time         // when the value was received
+	Labels   map[string]string // additional labels, see ValueDesc.Labels
+
+	event   bool // an event counted by adding the value to the event counter, see SendEvent
+	publish bool // publish the MQTT message of the sensor when stored, see SendState
 }
 
 // Register is a callback register, the Deregister func will be called as reg.Deregister(reg.ID)
@@ -94,6 +140,19 @@ type Device struct {
 	FirmwareVersion string
 	DeviceID        uint16
 	Available       bool
+	Supported       bool // false when there's no Driver for this device
+	LastEnumerated  time.Time
+
+	statusLED func(uint8) error // SetStatusLEDConfig of the device, nil if not available
+	scale     *scale            // load cell of the device, nil if not available
+}
+
+// positionString returns the position as label value, '0' - '8' for bricks, 'a' - 'd' for bricklets
+func (dev *Device) positionString() string {
+	if dev.Position == 0 {
+		return ""
+	}
+	return string(dev.Position)
 }
 
 // NewCollector creates a new collector for the given address (and authenticates with the password)
@@ -107,9 +166,12 @@ func NewCollector(addr, password string, cbPeriod time.Duration, ignoredUIDs []s
 		Data: &BrickData{
 			Address: addr,
 			Devices: make(map[string]*Device),
-			Values:  make(map[string]map[int]Value),
+			Values:  make(map[string]map[int64]Value),
 		},
 		Registry:       make(map[string][]Register),
+		registering:    make(map[string]bool),
+		eventCounts:    make(map[string]map[int64]float64),
+		Connection:     ipconnection.New(),
 		Values:         make(chan Value),
 		CallbackPeriod: uint32(cbPeriod / time.Millisecond),
 		IgnoredUIDs:    ignoredUIDs,
@@ -117,42 +179,33 @@ func NewCollector(addr, password string, cbPeriod time.Duration, ignoredUIDs []s
 		SensorLabels:   sensorLabels,
 		ExpirePeriod:   expirePeriod,
 		MQTT:           mq,
+
+		DisconnectCounter: make(map[string]int64),
+		done:              make(chan struct{}),
+		haRepeaters:       make(map[string]chan struct{}),
+		commands:          make(map[string]func([]byte)),
+	}
+	for _, reason := range disconnectReasons {
+		brickd.DisconnectCounter[reason] = 0
 	}
 
-	if brickd.MQTT.Enabled {
+	if brickd.MQTT.Enabled && brickd.MQTT.Client == nil {
+		// the client is shared by all collectors using the same MQTT config
 		var err error
 		brickd.MQTT.Client, err = mqtt.NewClient(brickd.MQTT.Broker)
 		if err != nil {
 			log.Warnf("failed to create MQTT client: %s", err)
-		} else {
-			go brickd.ExportMQTT(cbPeriod)
 		}
 	}
+	brickd.SetMQTT(brickd.MQTT)
 
-	brickd.Devices = map[uint16]RegisterFunc{
-		// Bricks
-		master_brick.DeviceIdentifier:   brickd.RegisterMasterBrick,
-		hat_zero_brick.DeviceIdentifier: brickd.RegisterZeroHatBrick,
-		hat_brick.DeviceIdentifier:      brickd.RegisterHatBrick,
-
-		// Bricklets
-		analog_in_v3_bricklet.DeviceIdentifier:     brickd.RegisterAnalogInV3Bricklet,
-		air_quality_bricklet.DeviceIdentifier:      brickd.RegisterAirQualityBricklet,
-		barometer_bricklet.DeviceIdentifier:        brickd.RegisterBarometerBricklet,
-		barometer_v2_bricklet.DeviceIdentifier:     brickd.RegisterBarometerV2Bricklet,
-		humidity_bricklet.DeviceIdentifier:         brickd.RegisterHumidityBricklet,
-		humidity_v2_bricklet.DeviceIdentifier:      brickd.RegisterHumidityV2Bricklet,
-		ambient_light_v3_bricklet.DeviceIdentifier: brickd.RegisterAmbientLightV3Bricklet,
-		co2_v2_bricklet.DeviceIdentifier:           brickd.RegisterCO2V2Bricklet,
-		uv_light_v2_bricklet.DeviceIdentifier:      brickd.RegisterUVLightV2Bricklet,
-
-		outdoor_weather_bricklet.DeviceIdentifier: brickd.RegisterOutdoorWeatherBricklet,
-	}
+	brickd.Drivers = Drivers()
 
 	go brickd.Update()
 	return brickd
 }
 
+// ignored returns true if the device should not be exported, b must be (read) locked
 func (b *BrickdCollector) ignored(uid string) bool {
 	for _, u := range b.IgnoredUIDs {
 		if uid == u {
@@ -162,9 +215,9 @@ func (b *BrickdCollector) ignored(uid string) bool {
 	return false
 }
 
-// Update runs in the background and discovers devices and collects the Values
+// Update runs in the background and discovers devices and collects the Values until the
+// collector is closed
 func (b *BrickdCollector) Update() {
-	b.Connection = ipconnection.New()
 	defer b.Connection.Close()
 	b.Connection.SetAutoReconnect(false) // set to true after first successful connection
 	b.Connection.RegisterEnumerateCallback(b.OnEnumerate)
@@ -178,42 +231,169 @@ func (b *BrickdCollector) Update() {
 			break
 		}
 		log.Infof("failed to connect to %s: %s", b.Address, err)
-		time.Sleep(time.Second)
+		select {
+		case <-b.done:
+			return
+		case <-time.After(time.Second):
+		}
 	}
 	defer b.Connection.Disconnect()
 
 	go func() { // discover eventually new bricks / bricklets on the brickd
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
 		for {
-			time.Sleep(time.Minute)
-			b.Connection.Enumerate()
+			select {
+			case <-b.done:
+				return
+			case <-ticker.C:
+				b.Connection.Enumerate()
+			}
 		}
 	}()
 
-	if b.ExpirePeriod != 0 {
-		go b.expireValues()
-	}
+	go b.expireValues()
 
-	for v := range b.Values {
-		if b.ignored(v.UID) {
-			continue
+	for {
+		var v Value
+		select {
+		case <-b.done:
+			return
+		case v = <-b.Values:
 		}
 		v.Received = time.Now()
 		b.Lock()
+		if b.ignored(v.UID) {
+			b.Unlock()
+			continue
+		}
 		log.Debugf("received value from \"%s\" (uid=%s, sensor=%d): %s=%f\n", DeviceName(v.DeviceID), v.UID, v.SensorID, v.Name, v.Value)
 		if _, ok := b.Data.Values[v.UID]; !ok {
-			b.Data.Values[v.UID] = make(map[int]Value)
+			b.Data.Values[v.UID] = make(map[int64]Value)
+		}
+		if v.event {
+			if _, ok := b.eventCounts[v.UID]; !ok {
+				b.eventCounts[v.UID] = make(map[int64]float64)
+			}
+			b.eventCounts[v.UID][v.Index] += v.Value
+			v.Value = b.eventCounts[v.UID][v.Index]
 		}
 		b.Data.Values[v.UID][v.Index] = v
 		b.Unlock()
+		if v.publish {
+			b.publishSensor(v.UID, v.SensorID)
+		}
 		// log.Debugf("DATA=%#v", b.Data.Values)
 	}
 }
 
+// Close disconnects from brickd and stops all background goroutines of the collector
+func (b *BrickdCollector) Close() {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+}
+
+// Reload applies the settings without reconnecting to brickd. Devices which are ignored now
+// are removed, a new enumeration registers the devices which are no longer ignored.
+func (b *BrickdCollector) Reload(s Settings) {
+	b.Lock()
+	defer b.Unlock()
+
+	leds := make(map[string]string)
+	for uid := range b.Data.Devices {
+		leds[uid] = b.ledStatus(uid)
+	}
+	wasIgnored := b.IgnoredUIDs
+	splChanged := b.SoundPressureLevel != s.SoundPressureLevel
+	oldTanks := b.Tanks
+	oldCounters := b.Counters
+	oldVoltageCurrent := b.VoltageCurrent
+	oldLoadCells := b.LoadCells
+	oldVibration := b.Vibration
+
+	b.IgnoredUIDs = s.IgnoredUIDs
+	b.Labels = s.Labels
+	b.SensorLabels = s.SensorLabels
+	b.ExpirePeriod = s.ExpirePeriod
+	b.LEDStatus = s.LEDStatus
+	b.DeviceLEDStatus = s.DeviceLEDStatus
+	b.ReceivedTimestamps = s.ReceivedTimestamps
+	b.ValueTimestamps = s.ValueTimestamps
+	b.OutdoorWeatherMaxAge = s.OutdoorWeatherMaxAge
+	b.SoundPressureLevel = s.SoundPressureLevel
+	b.Tanks = s.Tanks
+	b.Counters = s.Counters
+	b.VoltageCurrent = s.VoltageCurrent
+	b.LoadCells = s.LoadCells
+	b.Vibration = s.Vibration
+	for uid := range b.tareOverrides {
+		if oldLoadCells[uid].Tare != s.LoadCells[uid].Tare {
+			delete(b.tareOverrides, uid)
+		}
+	}
+
+	enumerate := false
+	for uid, dev := range b.Data.Devices {
+		if b.ignored(uid) {
+			log.Debugf("removing ignored device %s (uid=%s)", DeviceName(dev.DeviceID), uid)
+			b.removeDevice(uid)
+			continue
+		}
+		// the sound pressure level, counter, voltage/current, load cell and vibration settings
+		// are applied and the HA config of the tanks is published when registering, the device
+		// is registered again by the new enumeration
+		if splChanged && dev.DeviceID == sound_pressure_level_bricklet.DeviceIdentifier ||
+			!reflect.DeepEqual(oldTanks[uid], s.Tanks[uid]) ||
+			!reflect.DeepEqual(oldCounters[uid], s.Counters[uid]) ||
+			!reflect.DeepEqual(oldVoltageCurrent[uid], s.VoltageCurrent[uid]) ||
+			oldLoadCells[uid].MovingAverage != s.LoadCells[uid].MovingAverage ||
+			!reflect.DeepEqual(oldVibration[uid], s.Vibration[uid]) {
+			log.Debugf("removing device %s (uid=%s) to apply the new settings", DeviceName(dev.DeviceID), uid)
+			b.removeDevice(uid)
+			enumerate = true
+			continue
+		}
+		status := b.ledStatus(uid)
+		if dev.statusLED == nil || status == "" || status == leds[uid] {
+			continue
+		}
+		if err := dev.statusLED(StatusLEDConfigs[status]); err != nil {
+			log.Errorf("failed to set LED status for device %s: %s", uid, err)
+		}
+	}
+
+	for _, uid := range wasIgnored {
+		if !b.ignored(uid) {
+			enumerate = true
+			break
+		}
+	}
+	if enumerate {
+		b.Connection.Enumerate()
+	}
+}
+
 func (b *BrickdCollector) expireValues() {
-	log.Debugf("expiring values every %s", b.ExpirePeriod)
 	for {
-		time.Sleep(b.ExpirePeriod)
+		b.RLock()
+		period := b.ExpirePeriod
+		b.RUnlock()
+		if period == 0 { // disabled, check again later as it may be enabled by Reload
+			period = time.Second
+		}
+
+		select {
+		case <-b.done:
+			return
+		case <-time.After(period):
+		}
+
 		b.Lock()
+		if b.ExpirePeriod == 0 {
+			b.Unlock()
+			continue
+		}
 		expireDate := time.Now().Add(-1 * b.ExpirePeriod)
 		for uid := range b.Data.Values {
 			for i := range b.Data.Values[uid] {
@@ -227,81 +407,170 @@ func (b *BrickdCollector) expireValues() {
 	}
 }
 
-// Describe is part of the prometheus.Collector interface
-func (b *BrickdCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("dummy", "dummy", nil, nil)
-}
-
-// Collect is part of the prometheus.Collector interface
-func (b *BrickdCollector) Collect(ch chan<- prometheus.Metric) {
+// collect sends the current values of this brickd to ch, see MultiCollector.Collect
+func (b *BrickdCollector) collect(ch chan<- prometheus.Metric, d *descriptors) {
 	b.RLock()
 	defer b.RUnlock()
-	desc := prometheus.NewDesc(
-		"brickd_connections_total",
-		"Number of connections to brickd",
-		nil,
-		map[string]string{
-			"brickd": b.Data.Address,
-		},
-	)
 	ch <- prometheus.MustNewConstMetric(
-		desc,
+		d.connections,
 		prometheus.CounterValue,
 		float64(b.ConnectCounter),
+		b.Data.Address,
 	)
+	state := b.Connection.GetConnectionState()
+	ch <- prometheus.MustNewConstMetric(
+		d.up,
+		prometheus.GaugeValue,
+		bool2Float(state == ipconnection.ConnectionStateConnected),
+		b.Data.Address,
+	)
+	for s, name := range connectionStates {
+		ch <- prometheus.MustNewConstMetric(
+			d.connectionState,
+			prometheus.GaugeValue,
+			bool2Float(state == s),
+			b.Data.Address,
+			name,
+		)
+	}
+	for reason, count := range b.DisconnectCounter {
+		ch <- prometheus.MustNewConstMetric(
+			d.disconnects,
+			prometheus.CounterValue,
+			float64(count),
+			b.Data.Address,
+			reason,
+		)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		d.authFailures,
+		prometheus.CounterValue,
+		float64(b.AuthFailureCounter),
+		b.Data.Address,
+	)
+	if !b.LastConnect.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			d.lastConnect,
+			prometheus.GaugeValue,
+			float64(b.LastConnect.UnixNano())/1e9,
+			b.Data.Address,
+		)
+	}
+
+	for _, dev := range b.Data.Devices {
+		if b.ignored(dev.UID) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			d.deviceInfo,
+			prometheus.GaugeValue,
+			1,
+			b.Data.Address,
+			dev.UID,
+			strconv.FormatInt(int64(dev.DeviceID), 10),
+			DeviceName(dev.DeviceID),
+			dev.ConnectedUID,
+			dev.positionString(),
+			dev.HardwareVersion,
+			dev.FirmwareVersion,
+			strconv.FormatBool(dev.Available),
+			strconv.FormatBool(dev.Supported),
+		)
+		ch <- prometheus.MustNewConstMetric(
+			d.deviceLastEnumerated,
+			prometheus.GaugeValue,
+			float64(dev.LastEnumerated.UnixNano())/1e9,
+			b.Data.Address,
+			dev.UID,
+		)
+	}
 
 	for _, vals := range b.Data.Values {
 		for _, v := range vals {
 			if v.UID == "" || b.ignored(v.UID) {
 				continue
 			}
-			labels := map[string]string{
-				"uid":       v.UID,
-				"brickd":    b.Data.Address,
-				"id":        strconv.FormatInt(int64(v.DeviceID), 10),
-				"type":      DeviceName(v.DeviceID),
-				"sub_id":    strconv.Itoa(v.SensorID), // deprecated
-				"sensor_id": strconv.Itoa(v.SensorID),
+			desc, ok := d.values[v.Name]
+			if !ok {
+				log.Debugf("no metric declared for value %q of %s (uid=%s)", v.Name, DeviceName(v.DeviceID), v.UID)
+				continue
+			}
+			labelValues := d.labelValues(b.valueLabels(v))
+			m := prometheus.MustNewConstMetric(
+				desc,
+				d.metrics[v.Name].Type,
+				v.Value,
+				labelValues...,
+			)
+			if b.ValueTimestamps {
+				m = prometheus.NewMetricWithTimestamp(v.Received, m)
 			}
-			for k, v := range b.Labels {
+			ch <- m
+
+			if b.ReceivedTimestamps {
+				ch <- prometheus.MustNewConstMetric(
+					d.received,
+					prometheus.GaugeValue,
+					float64(v.Received.UnixNano())/1e9,
+					append(labelValues, d.metrics[v.Name].FQName())...,
+				)
+			}
+		}
+	}
+}
+
+// valueLabels returns the labels of a value
+func (b *BrickdCollector) valueLabels(v Value) map[string]string {
+	labels := map[string]string{
+		"uid":       v.UID,
+		"brickd":    b.Data.Address,
+		"id":        strconv.FormatInt(int64(v.DeviceID), 10),
+		"type":      DeviceName(v.DeviceID),
+		"sub_id":    strconv.FormatInt(v.SensorID, 10), // deprecated
+		"sensor_id": strconv.FormatInt(v.SensorID, 10),
+	}
+	for k, val := range v.Labels {
+		labels[k] = val
+	}
+	for k, v := range b.Labels {
+		if _, exists := labels[k]; exists {
+			continue
+		}
+		labels[k] = v
+	}
+
+	if sl, ok := b.SensorLabels[v.UID]; ok {
+		if l, ok := sl[strconv.FormatInt(v.SensorID, 10)]; ok {
+			for k, v := range l {
+				if k == "mqtt_topic" {
+					continue
+				}
 				if _, exists := labels[k]; exists {
 					continue
 				}
 				labels[k] = v
 			}
+		}
+	}
+	return labels
+}
 
-			if sl, ok := b.SensorLabels[v.UID]; ok {
-				if l, ok := sl[strconv.Itoa(v.SensorID)]; ok {
-					for k, v := range l {
-						if k == "mqtt_topic" {
-							continue
-						}
-						if _, exists := labels[k]; exists {
-							continue
-						}
-						labels[k] = v
-					}
+// labelNames returns the names of all labels of the `labels` and `sensor_labels` config which
+// may be set additionally to the baseLabels
+func labelNames(labels map[string]string, sensorLabels map[string]map[string]map[string]string) []string {
+	var names []string
+	for k := range labels {
+		names = append(names, k)
+	}
+	for _, sl := range sensorLabels {
+		for _, l := range sl {
+			for k := range l {
+				if k == "mqtt_topic" {
+					continue
 				}
+				names = append(names, k)
 			}
-
-			var promType string
-			switch v.Type {
-			case prometheus.CounterValue:
-				promType = "total"
-			case prometheus.GaugeValue:
-				promType = "value"
-			}
-			desc := prometheus.NewDesc(
-				"brickd_"+v.Name+"_"+promType,
-				v.Help,
-				nil,
-				labels,
-			)
-			ch <- prometheus.MustNewConstMetric(
-				desc,
-				v.Type,
-				v.Value,
-			)
 		}
 	}
+	return names
 }
@@ -0,0 +1,1657 @@
+package collector
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tinkerforge/go-api-bindings/accelerometer_v2_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/distance_us_v2_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/energy_monitor_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/gps_v2_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/hall_effect_v2_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/humidity_v2_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/imu_v3_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/industrial_counter_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/industrial_quad_relay_v2_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/io4_v2_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/isolator_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/load_cell_v2_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/master_brick"
+	"github.com/Tinkerforge/go-api-bindings/motion_detector_v2_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/one_wire_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/outdoor_weather_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/particulate_matter_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/ptc_v2_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/sound_pressure_level_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/thermocouple_v2_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/tilt_bricklet"
+	"github.com/Tinkerforge/go-api-bindings/voltage_current_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
+	"github.com/vetinari/brickd_exporter/internal/fakebrickd"
+	"github.com/vetinari/brickd_exporter/mqtt"
+)
+
+const waitTimeout = 10 * time.Second
+
+var (
+	testMaster = fakebrickd.Device{
+		UID:              "6qb",
+		Position:         '0',
+		HardwareVersion:  [3]uint8{3, 0, 0},
+		FirmwareVersion:  [3]uint8{2, 5, 1},
+		DeviceIdentifier: master_brick.DeviceIdentifier,
+	}
+	testHumidity = fakebrickd.Device{
+		UID:              "hum",
+		ConnectedUID:     "6qb",
+		Position:         'a',
+		HardwareVersion:  [3]uint8{1, 0, 0},
+		FirmwareVersion:  [3]uint8{2, 0, 4},
+		DeviceIdentifier: humidity_v2_bricklet.DeviceIdentifier,
+	}
+	testOutdoorWeather = fakebrickd.Device{
+		UID:              "ow1",
+		ConnectedUID:     "6qb",
+		Position:         'b',
+		DeviceIdentifier: outdoor_weather_bricklet.DeviceIdentifier,
+	}
+	testEnergyMonitor = fakebrickd.Device{
+		UID:              "em1",
+		ConnectedUID:     "6qb",
+		Position:         'c',
+		DeviceIdentifier: energy_monitor_bricklet.DeviceIdentifier,
+	}
+	testPTC = fakebrickd.Device{
+		UID:              "ptc",
+		ConnectedUID:     "6qb",
+		Position:         'd',
+		DeviceIdentifier: ptc_v2_bricklet.DeviceIdentifier,
+	}
+	testThermocouple = fakebrickd.Device{
+		UID:              "tc1",
+		ConnectedUID:     "6qb",
+		Position:         'a',
+		DeviceIdentifier: thermocouple_v2_bricklet.DeviceIdentifier,
+	}
+	testParticulateMatter = fakebrickd.Device{
+		UID:              "pm1",
+		ConnectedUID:     "6qb",
+		Position:         'b',
+		DeviceIdentifier: particulate_matter_bricklet.DeviceIdentifier,
+	}
+	testIO4 = fakebrickd.Device{
+		UID:              "io4",
+		ConnectedUID:     "6qb",
+		Position:         'c',
+		DeviceIdentifier: io4_v2_bricklet.DeviceIdentifier,
+	}
+	testQuadRelay = fakebrickd.Device{
+		UID:              "qr1",
+		ConnectedUID:     "6qb",
+		Position:         'd',
+		DeviceIdentifier: industrial_quad_relay_v2_bricklet.DeviceIdentifier,
+	}
+	testSoundPressureLevel = fakebrickd.Device{
+		UID:              "sp1",
+		ConnectedUID:     "6qb",
+		Position:         'a',
+		DeviceIdentifier: sound_pressure_level_bricklet.DeviceIdentifier,
+	}
+	testDistanceUS = fakebrickd.Device{
+		UID:              "us2",
+		ConnectedUID:     "6qb",
+		Position:         'b',
+		DeviceIdentifier: distance_us_v2_bricklet.DeviceIdentifier,
+	}
+	testCounter = fakebrickd.Device{
+		UID:              "ic1",
+		ConnectedUID:     "6qb",
+		Position:         'c',
+		DeviceIdentifier: industrial_counter_bricklet.DeviceIdentifier,
+	}
+	testOneWire = fakebrickd.Device{
+		UID:              "ow1",
+		ConnectedUID:     "6qb",
+		Position:         'd',
+		DeviceIdentifier: one_wire_bricklet.DeviceIdentifier,
+	}
+	testGPS = fakebrickd.Device{
+		UID:              "gp2",
+		ConnectedUID:     "6qb",
+		Position:         'a',
+		DeviceIdentifier: gps_v2_bricklet.DeviceIdentifier,
+	}
+	testVoltageCurrent = fakebrickd.Device{
+		UID:              "vc2",
+		ConnectedUID:     "6qb",
+		Position:         'b',
+		DeviceIdentifier: voltage_current_v2_bricklet.DeviceIdentifier,
+	}
+	testIsolator = fakebrickd.Device{
+		UID:              "is1",
+		ConnectedUID:     "6qb",
+		Position:         'c',
+		DeviceIdentifier: isolator_bricklet.DeviceIdentifier,
+	}
+	testLoadCell = fakebrickd.Device{
+		UID:              "Lc2",
+		ConnectedUID:     "6qb",
+		Position:         'd',
+		DeviceIdentifier: load_cell_v2_bricklet.DeviceIdentifier,
+	}
+	testMotionDetector = fakebrickd.Device{
+		UID:              "md2",
+		ConnectedUID:     "6qb",
+		Position:         'a',
+		DeviceIdentifier: motion_detector_v2_bricklet.DeviceIdentifier,
+	}
+	testHallEffect = fakebrickd.Device{
+		UID:              "he2",
+		ConnectedUID:     "6qb",
+		Position:         'b',
+		DeviceIdentifier: hall_effect_v2_bricklet.DeviceIdentifier,
+	}
+	testTilt = fakebrickd.Device{
+		UID:              "ti1",
+		ConnectedUID:     "6qb",
+		Position:         'c',
+		DeviceIdentifier: tilt_bricklet.DeviceIdentifier,
+	}
+	testIMU = fakebrickd.Device{
+		UID:              "im3",
+		ConnectedUID:     "6qb",
+		Position:         'a',
+		DeviceIdentifier: imu_v3_bricklet.DeviceIdentifier,
+	}
+	testAccelerometer = fakebrickd.Device{
+		UID:              "ac2",
+		ConnectedUID:     "6qb",
+		Position:         'b',
+		DeviceIdentifier: accelerometer_v2_bricklet.DeviceIdentifier,
+	}
+	testServo = fakebrickd.Device{ // no driver available
+		UID:              "srv",
+		Position:         '1',
+		DeviceIdentifier: 14,
+	}
+)
+
+func TestMain(m *testing.M) {
+	log.SetLevel(log.WarnLevel)
+	os.Exit(m.Run())
+}
+
+func newTestServer(t *testing.T, devices ...fakebrickd.Device) *fakebrickd.Server {
+	t.Helper()
+	srv, err := fakebrickd.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	for _, dev := range devices {
+		if err := srv.AddDevice(dev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return srv
+}
+
+func newTestCollector(t *testing.T, srv *fakebrickd.Server, password string, expire time.Duration,
+	sensorLabels map[string]map[string]map[string]string) *BrickdCollector {
+	t.Helper()
+	b := NewCollector(srv.Addr(), password, 100*time.Millisecond, nil,
+		map[string]string{"site": "test"}, sensorLabels, expire, &mqtt.MQTT{})
+	t.Cleanup(b.Close)
+	return b
+}
+
+// waitFor fails the test when cond is not true within waitTimeout
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitRegistered waits until the callbacks of the device are registered
+func waitRegistered(t *testing.T, b *BrickdCollector, uid string) {
+	t.Helper()
+	waitFor(t, "registration of "+uid, func() bool {
+		b.RLock()
+		defer b.RUnlock()
+		_, ok := b.Registry[uid]
+		return ok
+	})
+}
+
+// waitValueEquals waits until a value of the device equals want
+func waitValueEquals(t *testing.T, b *BrickdCollector, uid, name string, want float64) {
+	t.Helper()
+	waitFor(t, fmt.Sprintf("value %s=%f of %s", name, want, uid), func() bool {
+		b.RLock()
+		defer b.RUnlock()
+		for _, v := range b.Data.Values[uid] {
+			if v.Name == name && approx(v.Value, want) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// waitValue waits until a value of the device has been received
+func waitValue(t *testing.T, b *BrickdCollector, uid, name string) Value {
+	t.Helper()
+	var v Value
+	waitFor(t, "value "+name+" of "+uid, func() bool {
+		b.RLock()
+		defer b.RUnlock()
+		for _, val := range b.Data.Values[uid] {
+			if val.Name == name {
+				v = val
+				return true
+			}
+		}
+		return false
+	})
+	return v
+}
+
+func gather(t *testing.T, collectors ...*BrickdCollector) map[string][]*dto.Metric {
+	t.Helper()
+	mc, err := NewMultiCollector(collectors...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(mc); err != nil {
+		t.Fatal(err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := make(map[string][]*dto.Metric)
+	for _, f := range families {
+		metrics[f.GetName()] = f.GetMetric()
+	}
+	return metrics
+}
+
+// findMetric returns the first metric with all the given labels
+func findMetric(t *testing.T, metrics map[string][]*dto.Metric, name string, labels map[string]string) *dto.Metric {
+	t.Helper()
+Metrics:
+	for _, m := range metrics[name] {
+		have := make(map[string]string)
+		for _, l := range m.GetLabel() {
+			have[l.GetName()] = l.GetValue()
+		}
+		for k, v := range labels {
+			if have[k] != v {
+				continue Metrics
+			}
+		}
+		return m
+	}
+	t.Fatalf("no metric %s with labels %v", name, labels)
+	return nil
+}
+
+// approx compares scaled values
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func metricValue(m *dto.Metric) float64 {
+	switch {
+	case m.GetGauge() != nil:
+		return m.GetGauge().GetValue()
+	case m.GetCounter() != nil:
+		return m.GetCounter().GetValue()
+	}
+	return m.GetUntyped().GetValue()
+}
+
+func TestCollect(t *testing.T) {
+	srv := newTestServer(t, testMaster, testHumidity)
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testMaster.UID)
+	waitRegistered(t, b, testHumidity.UID)
+
+	srv.Callback(testHumidity.UID, uint8(humidity_v2_bricklet.FunctionCallbackHumidity), uint16(4512))
+	srv.Callback(testHumidity.UID, uint8(humidity_v2_bricklet.FunctionCallbackTemperature), int16(-250))
+	srv.Callback(testMaster.UID, uint8(master_brick.FunctionCallbackStackVoltage), uint16(5012))
+	waitValue(t, b, testHumidity.UID, "humidity")
+	waitValue(t, b, testHumidity.UID, "temperature")
+	waitValue(t, b, testMaster.UID, "stack_voltage")
+
+	metrics := gather(t, b)
+	for _, tc := range []struct {
+		name   string
+		labels map[string]string
+		value  float64
+	}{
+		{"brickd_up", nil, 1},
+		{"brickd_connections_total", nil, 1},
+		{"brickd_humidity_value", map[string]string{"uid": "hum", "sensor_id": "0", "site": "test"}, 45.12},
+		{"brickd_temperature_value", map[string]string{"uid": "hum", "type": "Humidity Bricklet 2.0"}, -2.5},
+		{"brickd_stack_voltage_value", map[string]string{"uid": "6qb", "brickd": srv.Addr()}, 5.012},
+		{"brickd_device_info", map[string]string{"uid": "hum", "connected_uid": "6qb", "position": "a",
+			"hardware_version": "1.0.0", "firmware_version": "2.0.4", "supported": "true"}, 1},
+	} {
+		if v := metricValue(findMetric(t, metrics, tc.name, tc.labels)); !approx(v, tc.value) {
+			t.Errorf("%s%v = %f, want %f", tc.name, tc.labels, v, tc.value)
+		}
+	}
+
+	// without led_status the LEDs are not changed
+	if reqs := srv.Requests(testHumidity.UID, uint8(humidity_v2_bricklet.FunctionSetStatusLEDConfig)); len(reqs) != 0 {
+		t.Errorf("status LED of %s changed: %v", testHumidity.UID, reqs)
+	}
+}
+
+func TestReservedLabels(t *testing.T) {
+	for _, name := range []string{"metric", "uid", "sensor_id", "axis"} {
+		_, err := NewMultiCollectorFor(Settings{Labels: map[string]string{name: "x"}})
+		if err == nil {
+			t.Errorf("label %q accepted", name)
+		}
+		sensorLabels := map[string]map[string]map[string]string{"6qb": {"0": {name: "x"}}}
+		if _, err := NewMultiCollectorFor(Settings{SensorLabels: sensorLabels}); err == nil {
+			t.Errorf("sensor label %q accepted", name)
+		}
+	}
+	if _, err := NewMultiCollectorFor(Settings{Labels: map[string]string{"room": "x"}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnsupportedDevice(t *testing.T) {
+	srv := newTestServer(t, testMaster, testServo)
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testMaster.UID)
+
+	mc, err := NewMultiCollector(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	waitFor(t, "unsupported device", func() bool {
+		for _, dev := range mc.DeviceList() {
+			if dev.UID == testServo.UID {
+				found = !dev.Supported && dev.Type == "Servo Brick" && dev.Position == "1"
+				return true
+			}
+		}
+		return false
+	})
+	if !found {
+		t.Errorf("%s not listed as unsupported Servo Brick: %+v", testServo.UID, mc.DeviceList())
+	}
+	findMetric(t, gather(t, b), "brickd_device_info", map[string]string{"uid": "srv", "supported": "false"})
+}
+
+func TestOutdoorWeather(t *testing.T) {
+	srv := newTestServer(t, testMaster, testOutdoorWeather)
+	var ids [60]uint8
+	ids[0] = 7
+	srv.Respond(testOutdoorWeather.UID, uint8(outdoor_weather_bricklet.FunctionGetStationIdentifiersLowLevel), uint16(1), uint16(0), ids)
+	ids[0] = 42
+	srv.Respond(testOutdoorWeather.UID, uint8(outdoor_weather_bricklet.FunctionGetSensorIdentifiersLowLevel), uint16(1), uint16(0), ids)
+
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testOutdoorWeather.UID)
+
+	srv.Callback(testOutdoorWeather.UID, uint8(outdoor_weather_bricklet.FunctionCallbackStationData),
+		uint8(7), int16(123), uint8(65), uint32(32), uint32(57), uint32(1234), uint8(outdoor_weather_bricklet.WindDirectionNNW), true)
+	srv.Callback(testOutdoorWeather.UID, uint8(outdoor_weather_bricklet.FunctionCallbackSensorData),
+		uint8(42), int16(-15), uint8(80))
+	waitValue(t, b, testOutdoorWeather.UID, "battery_low")
+	waitValueEquals(t, b, testOutdoorWeather.UID, "humidity", 80)
+
+	station := outdoorWeatherStationID(7)
+	if v := waitValue(t, b, testOutdoorWeather.UID, "rain"); v.SensorID != station || !approx(v.Value, 123.4) {
+		t.Errorf("rain = %+v, want 123.4 mm from sensor %d", v, station)
+	}
+
+	metrics := gather(t, b)
+	for _, tc := range []struct {
+		name     string
+		sensorID int64
+		value    float64
+	}{
+		{"brickd_temperature_value", outdoorWeatherStationID(7), 12.3},
+		{"brickd_wind_speed_value", outdoorWeatherStationID(7), 3.2},
+		{"brickd_rain_total", outdoorWeatherStationID(7), 123.4},
+		{"brickd_battery_low_value", outdoorWeatherStationID(7), 1},
+		{"brickd_temperature_value", outdoorWeatherSensorID(42), -1.5},
+		{"brickd_humidity_value", outdoorWeatherSensorID(42), 80},
+	} {
+		labels := map[string]string{"uid": "ow1", "sensor_id": strconv.FormatInt(tc.sensorID, 10)}
+		if v := metricValue(findMetric(t, metrics, tc.name, labels)); !approx(v, tc.value) {
+			t.Errorf("%s%v = %f, want %f", tc.name, labels, v, tc.value)
+		}
+	}
+}
+
+func TestOutdoorWeatherScan(t *testing.T) {
+	srv := newTestServer(t, testMaster, testOutdoorWeather)
+	var ids [60]uint8
+	ids[0] = 42
+	srv.Respond(testOutdoorWeather.UID, uint8(outdoor_weather_bricklet.FunctionGetSensorIdentifiersLowLevel), uint16(1), uint16(0), ids)
+	srv.Respond(testOutdoorWeather.UID, uint8(outdoor_weather_bricklet.FunctionGetSensorData), int16(0), uint8(0), uint16(5))
+
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testOutdoorWeather.UID)
+	if v := waitValue(t, b, testOutdoorWeather.UID, "outdoor_weather_last_seen"); v.SensorID != outdoorWeatherSensorID(42) || v.Value != 5 {
+		t.Errorf("last seen = %+v, want 5s from sensor %d", v, outdoorWeatherSensorID(42))
+	}
+
+	// a new sensor is found by the next scan
+	ids[1] = 43
+	srv.Respond(testOutdoorWeather.UID, uint8(outdoor_weather_bricklet.FunctionGetSensorIdentifiersLowLevel), uint16(2), uint16(0), ids)
+	waitFor(t, "new sensor", func() bool {
+		return len(gather(t, b)["brickd_outdoor_weather_last_seen_seconds"]) == 2
+	})
+	findMetric(t, gather(t, b), "brickd_outdoor_weather_last_seen_seconds",
+		map[string]string{"uid": "ow1", "sensor_id": strconv.FormatInt(outdoorWeatherSensorID(43), 10)})
+
+	// sensors not seen for longer than the max age are removed
+	b.Lock()
+	b.OutdoorWeatherMaxAge = time.Minute
+	b.Unlock()
+	srv.Respond(testOutdoorWeather.UID, uint8(outdoor_weather_bricklet.FunctionGetSensorData), int16(0), uint8(0), uint16(3600))
+	waitFor(t, "removal of stale sensors", func() bool {
+		return len(gather(t, b)["brickd_outdoor_weather_last_seen_seconds"]) == 0
+	})
+}
+
+func TestEnergyMonitor(t *testing.T) {
+	srv := newTestServer(t, testMaster, testEnergyMonitor)
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testEnergyMonitor.UID)
+
+	srv.Callback(testEnergyMonitor.UID, uint8(energy_monitor_bricklet.FunctionCallbackEnergyData),
+		int32(23012), int32(152), int32(123456), int32(34000), int32(35000), int32(800), uint16(971), uint16(5001))
+	waitValue(t, b, testEnergyMonitor.UID, "frequency")
+
+	metrics := gather(t, b)
+	for _, tc := range []struct {
+		name  string
+		value float64
+	}{
+		{"brickd_voltage_value", 230.12},
+		{"brickd_current_value", 1.52},
+		{"brickd_energy_total", 1234.56},
+		{"brickd_real_power_value", 340},
+		{"brickd_power_factor_value", 0.971},
+		{"brickd_frequency_value", 50.01},
+	} {
+		labels := map[string]string{"uid": "em1"}
+		if v := metricValue(findMetric(t, metrics, tc.name, labels)); !approx(v, tc.value) {
+			t.Errorf("%s%v = %f, want %f", tc.name, labels, v, tc.value)
+		}
+	}
+}
+
+func TestProbeFaults(t *testing.T) {
+	srv := newTestServer(t, testMaster, testPTC, testThermocouple)
+	srv.Respond(testPTC.UID, uint8(ptc_v2_bricklet.FunctionIsSensorConnected), false)
+	srv.Respond(testPTC.UID, uint8(ptc_v2_bricklet.FunctionGetWireMode), uint8(ptc_v2_bricklet.WireMode3))
+	srv.Respond(testThermocouple.UID, uint8(thermocouple_v2_bricklet.FunctionGetErrorState), false, true)
+
+	expire := 500 * time.Millisecond
+	b := newTestCollector(t, srv, "", expire, nil)
+	waitRegistered(t, b, testPTC.UID)
+	waitRegistered(t, b, testThermocouple.UID)
+	waitValue(t, b, testPTC.UID, "wire_mode")
+	waitValue(t, b, testThermocouple.UID, "open_circuit")
+
+	// the states are polled, so they don't expire without callbacks
+	time.Sleep(3 * expire)
+	metrics := gather(t, b)
+	for _, tc := range []struct {
+		name  string
+		uid   string
+		value float64
+	}{
+		{"brickd_sensor_connected_value", "ptc", 0},
+		{"brickd_wire_mode_value", "ptc", 3},
+		{"brickd_over_under_voltage_value", "tc1", 0},
+		{"brickd_open_circuit_value", "tc1", 1},
+	} {
+		labels := map[string]string{"uid": tc.uid}
+		if v := metricValue(findMetric(t, metrics, tc.name, labels)); !approx(v, tc.value) {
+			t.Errorf("%s%v = %f, want %f", tc.name, labels, v, tc.value)
+		}
+	}
+
+	// the probes are connected again
+	srv.Respond(testPTC.UID, uint8(ptc_v2_bricklet.FunctionIsSensorConnected), true)
+	srv.Respond(testThermocouple.UID, uint8(thermocouple_v2_bricklet.FunctionGetErrorState), false, false)
+	srv.Callback(testPTC.UID, uint8(ptc_v2_bricklet.FunctionCallbackSensorConnected), true)
+	srv.Callback(testPTC.UID, uint8(ptc_v2_bricklet.FunctionCallbackTemperature), int32(-1850))
+	srv.Callback(testThermocouple.UID, uint8(thermocouple_v2_bricklet.FunctionCallbackErrorState), false, false)
+	srv.Callback(testThermocouple.UID, uint8(thermocouple_v2_bricklet.FunctionCallbackTemperature), int32(98765))
+	waitFor(t, "probes connected", func() bool {
+		b.RLock()
+		defer b.RUnlock()
+		return b.Data.Values["ptc"][int64(ptcV2SensorConnected.Index)].Value == 1 &&
+			b.Data.Values["tc1"][int64(thermocoupleV2OpenCircuit.Index)].Value == 0
+	})
+	temps := map[string]float64{"ptc": -18.5, "tc1": 987.65}
+	for uid, want := range temps {
+		if v := waitValue(t, b, uid, "temperature"); !approx(v.Value, want) {
+			t.Errorf("temperature of %s = %f, want %f", uid, v.Value, want)
+		}
+	}
+}
+
+func TestParticulateMatter(t *testing.T) {
+	srv := newTestServer(t, testMaster, testParticulateMatter)
+	srv.Respond(testParticulateMatter.UID, uint8(particulate_matter_bricklet.FunctionGetEnable), true)
+
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testParticulateMatter.UID)
+	waitFor(t, "sensor enabled", func() bool {
+		b.RLock()
+		defer b.RUnlock()
+		return b.Data.Values["pm1"][int64(particulateMatterEnabled.Index)].Value == 1
+	})
+
+	srv.Callback(testParticulateMatter.UID, uint8(particulate_matter_bricklet.FunctionCallbackPMConcentration),
+		uint16(3), uint16(7), uint16(12))
+	srv.Callback(testParticulateMatter.UID, uint8(particulate_matter_bricklet.FunctionCallbackPMCount),
+		uint16(1200), uint16(400), uint16(80), uint16(10), uint16(2), uint16(0))
+
+	// the bindings run each callback in its own goroutine, so all values are waited for
+	want := []struct {
+		name  string
+		value float64
+	}{
+		{"pm1", 3},
+		{"pm25", 7},
+		{"pm10", 12},
+		{"particles_0_3um", 1200},
+		{"particles_5_0um", 2},
+		{"particles_10_0um", 0},
+		{"particulate_matter_sensor_enabled", 1},
+	}
+	for _, tc := range want {
+		waitValue(t, b, testParticulateMatter.UID, tc.name)
+	}
+
+	metrics := gather(t, b)
+	for _, tc := range want {
+		name := "brickd_" + tc.name + "_value"
+		labels := map[string]string{"uid": "pm1"}
+		if v := metricValue(findMetric(t, metrics, name, labels)); !approx(v, tc.value) {
+			t.Errorf("%s%v = %f, want %f", name, labels, v, tc.value)
+		}
+	}
+
+	// the last known values are not exported while the sensor is disabled
+	srv.Respond(testParticulateMatter.UID, uint8(particulate_matter_bricklet.FunctionGetEnable), false)
+	waitFor(t, "removal of the measurements", func() bool {
+		metrics := gather(t, b)
+		enabled := metrics["brickd_particulate_matter_sensor_enabled_value"]
+		return len(metrics["brickd_pm25_value"]) == 0 && len(enabled) == 1 && metricValue(enabled[0]) == 0
+	})
+}
+
+func TestDigitalInputs(t *testing.T) {
+	srv := newTestServer(t, testMaster, testIO4)
+	var mu sync.Mutex
+	counts := [4]uint32{10, 0, 0, 0}
+	srv.Handle(testIO4.UID, uint8(io4_v2_bricklet.FunctionGetEdgeCount), func(payload []byte) []byte {
+		if payload[0] == 2 { // configured as output, answered with an error
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return fakebrickd.Encode(counts[payload[0]])
+	})
+	setCount := func(ch int, count uint32) {
+		mu.Lock()
+		counts[ch] = count
+		mu.Unlock()
+	}
+
+	b := newTestCollector(t, srv, "", 0, map[string]map[string]map[string]string{
+		"io4": {"1": {"door": "front"}},
+	})
+	waitRegistered(t, b, testIO4.UID)
+	waitValue(t, b, testIO4.UID, "digital_input_edges")
+	edges := func(ch int) float64 {
+		m := findMetric(t, gather(t, b), "brickd_digital_input_edges_total", map[string]string{"sensor_id": strconv.Itoa(ch)})
+		return metricValue(m)
+	}
+
+	// bool arrays are sent as bit fields: all changed, channels 1 and 2 high
+	srv.Callback(testIO4.UID, uint8(io4_v2_bricklet.FunctionCallbackAllInputValue), uint8(0x0f), uint8(0x06))
+	waitValue(t, b, testIO4.UID, "digital_input")
+
+	metrics := gather(t, b)
+	if n := len(metrics["brickd_digital_input_value"]); n != 3 {
+		t.Errorf("got %d digital inputs, want 3 without the output channel", n)
+	}
+	if v := metricValue(findMetric(t, metrics, "brickd_digital_input_value", map[string]string{"sensor_id": "1", "door": "front"})); v != 1 {
+		t.Errorf("level of channel 1 = %f, want 1", v)
+	}
+	if v := metricValue(findMetric(t, metrics, "brickd_digital_input_value", map[string]string{"sensor_id": "3"})); v != 0 {
+		t.Errorf("level of channel 3 = %f, want 0", v)
+	}
+
+	// the counter keeps increasing when the bricklet is reset
+	setCount(0, 15)
+	waitFor(t, "edge count 15", func() bool { return edges(0) == 15 })
+	setCount(0, 3)
+	waitFor(t, "edge count after reset", func() bool { return edges(0) == 18 })
+}
+
+func TestOutputs(t *testing.T) {
+	srv := newTestServer(t, testMaster, testQuadRelay)
+	srv.Respond(testQuadRelay.UID, uint8(industrial_quad_relay_v2_bricklet.FunctionGetValue), uint8(0x05)) // channels 0 and 2 on
+
+	b := newTestCollector(t, srv, "", 0, map[string]map[string]map[string]string{
+		"qr1": {"1": {"mqtt_topic": "ventilation"}},
+	})
+	waitRegistered(t, b, testQuadRelay.UID)
+	waitFor(t, "output states", func() bool {
+		return len(gather(t, b)["brickd_output_value"]) == 4
+	})
+	for ch, want := range []float64{1, 0, 1, 0} {
+		m := findMetric(t, gather(t, b), "brickd_output_value", map[string]string{"uid": "qr1", "sensor_id": strconv.Itoa(ch)})
+		if v := metricValue(m); v != want {
+			t.Errorf("output of channel %d = %f, want %f", ch, v, want)
+		}
+	}
+
+	b.RLock()
+	cmd, ok := b.commands["ventilation/set"]
+	_, ok0 := b.commands["industrial_quad_relay_bricklet_2_0_qr1/0/set"]
+	b.RUnlock()
+	if !ok || !ok0 {
+		t.Fatalf("missing command topics: %v", b.commands)
+	}
+	cmd([]byte("ON"))
+	req, err := srv.WaitForRequest(testQuadRelay.UID, uint8(industrial_quad_relay_v2_bricklet.FunctionSetSelectedValue), waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(req.Payload) != string(fakebrickd.Encode(uint8(1), true)) {
+		t.Errorf("set selected value payload = %v, want channel 1 on", req.Payload)
+	}
+	cmd([]byte("toggle")) // ignored
+	if n := len(srv.Requests(testQuadRelay.UID, uint8(industrial_quad_relay_v2_bricklet.FunctionSetSelectedValue))); n != 1 {
+		t.Errorf("got %d requests to switch, want 1", n)
+	}
+
+	// the subscriptions end with the device
+	srv.RemoveDevice(testQuadRelay.UID)
+	waitFor(t, "removal of the commands", func() bool {
+		b.RLock()
+		defer b.RUnlock()
+		return len(b.commands) == 0
+	})
+}
+
+func TestSoundPressureLevel(t *testing.T) {
+	srv := newTestServer(t, testMaster, testSoundPressureLevel)
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testSoundPressureLevel.UID)
+
+	srv.Callback(testSoundPressureLevel.UID, uint8(sound_pressure_level_bricklet.FunctionCallbackDecibel), uint16(453))
+	waitValue(t, b, testSoundPressureLevel.UID, "sound_pressure_level")
+	m := findMetric(t, gather(t, b), "brickd_sound_pressure_level_value", map[string]string{"uid": "sp1", "weighting": "A"})
+	if v := metricValue(m); !approx(v, 45.3) {
+		t.Errorf("sound pressure level = %f, want 45.3", v)
+	}
+
+	// the device is registered again with the new settings
+	b.Reload(Settings{
+		SensorLabels:       map[string]map[string]map[string]string{"sp1": {"0": {"mqtt_topic": "noise"}}},
+		SoundPressureLevel: SoundPressureLevelSettings{FFTSize: 128, Weighting: "C", Spectrum: true},
+	})
+	waitFor(t, "new configuration", func() bool {
+		for _, req := range srv.Requests(testSoundPressureLevel.UID, uint8(sound_pressure_level_bricklet.FunctionSetConfiguration)) {
+			if req.Payload[0] == sound_pressure_level_bricklet.FFTSize128 && req.Payload[1] == sound_pressure_level_bricklet.WeightingC {
+				return true
+			}
+		}
+		return false
+	})
+	waitRegistered(t, b, testSoundPressureLevel.UID)
+
+	// 64 bins of 320 Hz each, sent in chunks of 30
+	var spectrum [90]uint16
+	spectrum[0] = 5000 // DC offset
+	spectrum[1] = 1414 // 60 dB at 320 Hz
+	spectrum[63] = 141 // 40 dB at 20160 Hz
+	for offset := 0; offset < 64; offset += 30 {
+		var chunk [30]uint16
+		copy(chunk[:], spectrum[offset:])
+		srv.Callback(testSoundPressureLevel.UID, uint8(sound_pressure_level_bricklet.FunctionCallbackSpectrumLowLevel),
+			uint16(64), uint16(offset), chunk)
+	}
+	waitFor(t, "spectrum", func() bool {
+		return len(gather(t, b)["brickd_sound_spectrum_value"]) == 63
+	})
+	metrics := gather(t, b)
+	for freq, want := range map[string]float64{"320": 60, "640": 0, "20160": 40} {
+		m := findMetric(t, metrics, "brickd_sound_spectrum_value", map[string]string{"weighting": "C", "frequency": freq})
+		if v := metricValue(m); math.Abs(v-want) > 0.05 {
+			t.Errorf("spectrum at %s Hz = %f, want %f", freq, v, want)
+		}
+	}
+
+	srv.Callback(testSoundPressureLevel.UID, uint8(sound_pressure_level_bricklet.FunctionCallbackDecibel), uint16(612))
+	waitFor(t, "C weighted value", func() bool {
+		return len(gather(t, b)["brickd_sound_pressure_level_value"]) == 1
+	})
+	for _, msg := range b.mqttMessages() {
+		if msg.Topic != "noise" {
+			continue
+		}
+		var p struct {
+			Spectrum []float64         `json:"sound_spectrum"`
+			Labels   map[string]string `json:"labels"`
+		}
+		if err := json.Unmarshal(msg.Payload, &p); err != nil {
+			t.Fatalf("invalid payload for %s: %s", msg.Topic, err)
+		}
+		if len(p.Spectrum) != 63 || math.Abs(p.Spectrum[0]-60) > 0.01 {
+			t.Errorf("MQTT spectrum = %v, want 63 bins starting with 60", p.Spectrum)
+		}
+		if p.Labels["weighting"] != "C" || p.Labels["frequency"] != "" {
+			t.Errorf("MQTT labels = %v, want weighting C without frequency", p.Labels)
+		}
+		return
+	}
+	t.Errorf("no MQTT message for the sound pressure level bricklet")
+}
+
+func TestTank(t *testing.T) {
+	srv := newTestServer(t, testMaster, testDistanceUS)
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testDistanceUS.UID)
+
+	srv.Callback(testDistanceUS.UID, uint8(distance_us_v2_bricklet.FunctionCallbackDistance), uint16(500))
+	waitValue(t, b, testDistanceUS.UID, "distance")
+	if metrics := gather(t, b); len(metrics["brickd_tank_level_value"]) != 0 {
+		t.Errorf("tank level exported without tank settings")
+	}
+
+	tank := TankSettings{
+		Height:  2,
+		Volumes: []TankVolume{{Level: 0, Volume: 0}, {Level: 1, Volume: 1000}, {Level: 2, Volume: 3000}},
+	}
+	if err := tank.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	b.Reload(Settings{Tanks: map[string]TankSettings{testDistanceUS.UID: tank}})
+	waitRegistered(t, b, testDistanceUS.UID)
+	if values := b.tankValues(&Device{UID: testDistanceUS.UID}); len(values) != 3 {
+		t.Errorf("HA config published for %d values, want 3", len(values))
+	}
+
+	srv.Callback(testDistanceUS.UID, uint8(distance_us_v2_bricklet.FunctionCallbackDistance), uint16(500))
+	waitValue(t, b, testDistanceUS.UID, "tank_volume")
+	metrics := gather(t, b)
+	for _, tc := range []struct {
+		name  string
+		value float64
+	}{
+		{"brickd_distance_value", 0.5},
+		{"brickd_tank_level_value", 1.5},
+		{"brickd_tank_volume_value", 2000},
+	} {
+		if v := metricValue(findMetric(t, metrics, tc.name, map[string]string{"uid": "us2"})); !approx(v, tc.value) {
+			t.Errorf("%s = %f, want %f", tc.name, v, tc.value)
+		}
+	}
+
+	// tanks with vertical walls, the level is limited to the height
+	cylinder := TankSettings{Height: 2, Capacity: 4000}
+	for distance, want := range map[float64]float64{0.5: 3000, 2.5: 0, 0: 4000} {
+		if v := cylinder.volume(cylinder.level(distance)); !approx(v, want) {
+			t.Errorf("volume at distance %.1f = %f, want %f", distance, v, want)
+		}
+	}
+	if err := (TankSettings{Height: 1, Volumes: []TankVolume{{Level: 1}, {Level: 0.5}}}).Validate(); err == nil {
+		t.Errorf("unsorted volumes accepted")
+	}
+}
+
+func TestIndustrialCounter(t *testing.T) {
+	srv := newTestServer(t, testMaster, testCounter)
+	// counter 3 is disabled on the bricklet
+	srv.Respond(testCounter.UID, uint8(industrial_counter_bricklet.FunctionGetAllCounterActive), uint8(0x07))
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testCounter.UID)
+
+	inactive := false
+	counters := map[string]map[string]CounterSettings{
+		"ic1": {
+			"0": {Name: "water", Unit: "L", DeviceClass: "water", PulsesPerUnit: 2, Edge: "falling"},
+			"1": {Active: &inactive},
+		},
+	}
+	for _, c := range counters["ic1"] {
+		if err := c.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.Reload(Settings{Counters: counters})
+	waitRegistered(t, b, testCounter.UID)
+	waitFor(t, "channel 0 counting on the falling edge", func() bool {
+		for _, req := range srv.Requests(testCounter.UID, uint8(industrial_counter_bricklet.FunctionSetCounterConfiguration)) {
+			if req.Payload[0] == 0 && req.Payload[1] == industrial_counter_bricklet.CountEdgeFalling {
+				return true
+			}
+		}
+		return false
+	})
+
+	srv.Callback(testCounter.UID, uint8(industrial_counter_bricklet.FunctionCallbackAllCounter), [4]int64{10, 5, 7, 0})
+	srv.Callback(testCounter.UID, uint8(industrial_counter_bricklet.FunctionCallbackAllSignalData),
+		[4]uint16{5000, 0, 0, 0}, [4]uint64{}, [4]uint32{50000, 0, 0, 0}, uint8(0x01))
+	waitValue(t, b, testCounter.UID, "water")
+	waitValue(t, b, testCounter.UID, "industrial_counter_frequency")
+
+	metrics := gather(t, b)
+	for _, tc := range []struct {
+		name    string
+		channel string
+		value   float64
+	}{
+		{"brickd_water_total", "0", 5},
+		{"brickd_industrial_counter_total", "2", 7},
+		{"brickd_industrial_counter_frequency_value", "0", 50},
+		{"brickd_industrial_counter_duty_cycle_value", "0", 50},
+		{"brickd_digital_input_value", "0", 1},
+	} {
+		labels := map[string]string{"uid": "ic1", "sensor_id": tc.channel}
+		if v := metricValue(findMetric(t, metrics, tc.name, labels)); !approx(v, tc.value) {
+			t.Errorf("%s%v = %f, want %f", tc.name, labels, v, tc.value)
+		}
+	}
+	for _, m := range metrics["brickd_industrial_counter_total"] {
+		for _, l := range m.GetLabel() {
+			if l.GetName() == "sensor_id" && (l.GetValue() == "1" || l.GetValue() == "3") {
+				t.Errorf("inactive counter %s exported", l.GetValue())
+			}
+		}
+	}
+
+	if err := (CounterSettings{Name: "humidity"}).Validate(); err == nil {
+		t.Errorf("counter named like the humidity value accepted")
+	}
+}
+
+func TestOneWire(t *testing.T) {
+	if crc := crc8([]byte{0x50, 0x05, 0x4b, 0x46, 0x7f, 0xff, 0x0c, 0x10}); crc != 0x1c {
+		t.Errorf("crc8 = %#x, want 0x1c", crc)
+	}
+
+	probe := func(serial uint64) uint64 { return 0x28 | serial<<8 | 0xaa<<56 }
+	scratchpad := func(raw int16) []byte {
+		p := []byte{byte(raw), byte(uint16(raw) >> 8), 0x4b, 0x46, 0x7f, 0xff, 0x0c, 0x10}
+		return append(p, crc8(p))
+	}
+	kitchen, freezer, broken := probe(0x75b1f5a), probe(0x75b2000), probe(0x75b3000)
+	ds18s20 := uint64(0x10 | 0x1234<<8) // other family, ignored
+	scratchpads := map[uint64][]byte{
+		kitchen: scratchpad(344),  // 21.5 °C
+		freezer: scratchpad(-290), // -18.125 °C
+		broken:  append(scratchpad(400)[:8], 0),
+	}
+
+	srv := newTestServer(t, testMaster, testOneWire)
+	var mu sync.Mutex
+	roms := []uint64{kitchen, freezer, broken, ds18s20}
+	var selected uint64
+	var pos int
+	srv.Handle(testOneWire.UID, uint8(one_wire_bricklet.FunctionSearchBusLowLevel), func([]byte) []byte {
+		mu.Lock()
+		defer mu.Unlock()
+		var chunk [7]uint64
+		copy(chunk[:], roms)
+		return fakebrickd.Encode(uint16(len(roms)), uint16(0), chunk, uint8(one_wire_bricklet.StatusOK))
+	})
+	srv.Handle(testOneWire.UID, uint8(one_wire_bricklet.FunctionWriteCommand), func(payload []byte) []byte {
+		mu.Lock()
+		defer mu.Unlock()
+		selected, pos = binary.LittleEndian.Uint64(payload), 0
+		return fakebrickd.Encode(uint8(one_wire_bricklet.StatusOK))
+	})
+	srv.Handle(testOneWire.UID, uint8(one_wire_bricklet.FunctionRead), func([]byte) []byte {
+		mu.Lock()
+		defer mu.Unlock()
+		data := scratchpads[selected][pos]
+		pos++
+		return fakebrickd.Encode(data, uint8(one_wire_bricklet.StatusOK))
+	})
+
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testOneWire.UID)
+	waitFor(t, "temperatures of the probes", func() bool {
+		return len(gather(t, b)["brickd_temperature_value"]) == 2
+	})
+	metrics := gather(t, b)
+	for _, tc := range []struct {
+		rom   uint64
+		value float64
+	}{
+		{kitchen, 21.5},
+		{freezer, -18.125},
+	} {
+		labels := map[string]string{"uid": "ow1", "sensor_id": strconv.FormatInt(oneWireSensorID(tc.rom), 10), "rom": oneWireROM(tc.rom)}
+		if v := metricValue(findMetric(t, metrics, "brickd_temperature_value", labels)); !approx(v, tc.value) {
+			t.Errorf("temperature of %s = %f, want %f", oneWireROM(tc.rom), v, tc.value)
+		}
+	}
+	if rom := oneWireROM(kitchen); rom != "28-0000075b1f5a" {
+		t.Errorf("ROM = %s, want 28-0000075b1f5a", rom)
+	}
+	if id, err := OneWireSensorID("28-0000075b1f5a"); err != nil || id != strconv.FormatInt(oneWireSensorID(kitchen), 10) {
+		t.Errorf("sensor id of 28-0000075b1f5a = %s (%v), want %d", id, err, oneWireSensorID(kitchen))
+	}
+	if _, err := OneWireSensorID("28-0000075b1f5g"); err == nil {
+		t.Errorf("invalid ROM ID accepted")
+	}
+
+	// probes no longer found are removed
+	mu.Lock()
+	roms = []uint64{kitchen}
+	mu.Unlock()
+	waitFor(t, "removal of the freezer probe", func() bool {
+		return len(gather(t, b)["brickd_temperature_value"]) == 1
+	})
+}
+
+func TestGPS(t *testing.T) {
+	srv := newTestServer(t, testMaster, testGPS)
+	srv.Handle(testGPS.UID, uint8(gps_v2_bricklet.FunctionGetSatelliteSystemStatusLowLevel), func(payload []byte) []byte {
+		if payload[0] == gps_v2_bricklet.SatelliteSystemGPS {
+			return fakebrickd.Encode(uint8(5), [12]uint8{3, 7, 12, 0, 21}, gps_v2_bricklet.Fix3DFix, uint16(180), uint16(95), uint16(150))
+		}
+		return fakebrickd.Encode(uint8(2), [12]uint8{65, 70}, gps_v2_bricklet.Fix3DFix, uint16(180), uint16(95), uint16(150))
+	})
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testGPS.UID)
+
+	// the GPS time is 2.5s ahead
+	gps := time.Now().UTC().Add(2500 * time.Millisecond)
+	date := uint32(gps.Day()*10000 + int(gps.Month())*100 + gps.Year()%100)
+	tm := uint32(gps.Hour()*10000000 + gps.Minute()*100000 + gps.Second()*1000 + gps.Nanosecond()/1e6)
+	srv.Callback(testGPS.UID, uint8(gps_v2_bricklet.FunctionCallbackCoordinates), uint32(52520008), uint8('N'), uint32(13404954), uint8('W'))
+	srv.Callback(testGPS.UID, uint8(gps_v2_bricklet.FunctionCallbackAltitude), int32(3450), int32(4000))
+	srv.Callback(testGPS.UID, uint8(gps_v2_bricklet.FunctionCallbackMotion), uint32(27050), uint32(1234))
+	srv.Callback(testGPS.UID, uint8(gps_v2_bricklet.FunctionCallbackStatus), true, uint8(11))
+	srv.Callback(testGPS.UID, uint8(gps_v2_bricklet.FunctionCallbackDateTime), date, tm)
+	for _, name := range []string{"longitude", "altitude", "speed", "gps_satellites_in_view", "gps_satellites_used", "gps_time_offset"} {
+		waitValue(t, b, testGPS.UID, name)
+	}
+
+	metrics := gather(t, b)
+	for _, tc := range []struct {
+		name  string
+		value float64
+	}{
+		{"brickd_latitude_value", 52.520008},
+		{"brickd_longitude_value", -13.404954},
+		{"brickd_altitude_value", 34.5},
+		{"brickd_speed_value", 12.34},
+		{"brickd_course_value", 270.5},
+		{"brickd_gps_satellites_in_view_value", 11},
+		{"brickd_gps_satellites_used_value", 6},
+		{"brickd_gps_fix_value", 3},
+		{"brickd_gps_hdop_value", 0.95},
+	} {
+		if v := metricValue(findMetric(t, metrics, tc.name, map[string]string{"uid": "gp2"})); !approx(v, tc.value) {
+			t.Errorf("%s = %f, want %f", tc.name, v, tc.value)
+		}
+	}
+	if v := metricValue(findMetric(t, metrics, "brickd_gps_time_offset_seconds", nil)); math.Abs(v-2.5) > 0.5 {
+		t.Errorf("GPS time offset = %f, want about 2.5", v)
+	}
+}
+
+func TestGPSStationary(t *testing.T) {
+	srv := newTestServer(t, testMaster, testGPS)
+	srv.Respond(testGPS.UID, uint8(gps_v2_bricklet.FunctionGetStatus), true, uint8(9))
+	srv.Respond(testGPS.UID, uint8(gps_v2_bricklet.FunctionGetCoordinates), uint32(52520008), uint8('N'), uint32(13404954), uint8('E'))
+	srv.Respond(testGPS.UID, uint8(gps_v2_bricklet.FunctionGetAltitude), int32(3450), int32(4000))
+	srv.Respond(testGPS.UID, uint8(gps_v2_bricklet.FunctionGetMotion), uint32(0), uint32(0))
+
+	expire := 500 * time.Millisecond
+	b := newTestCollector(t, srv, "", expire, nil)
+	waitRegistered(t, b, testGPS.UID)
+	waitValue(t, b, testGPS.UID, "speed")
+
+	// the position is polled, so it doesn't expire while the callbacks are not triggered
+	time.Sleep(3 * expire)
+	metrics := gather(t, b)
+	for _, tc := range []struct {
+		name  string
+		value float64
+	}{
+		{"brickd_latitude_value", 52.520008},
+		{"brickd_longitude_value", 13.404954},
+		{"brickd_altitude_value", 34.5},
+		{"brickd_speed_value", 0},
+		{"brickd_course_value", 0},
+		{"brickd_gps_satellites_in_view_value", 9},
+	} {
+		if v := metricValue(findMetric(t, metrics, tc.name, map[string]string{"uid": "gp2"})); !approx(v, tc.value) {
+			t.Errorf("%s after %s = %f, want %f", tc.name, 3*expire, v, tc.value)
+		}
+	}
+}
+
+func TestVoltageCurrent(t *testing.T) {
+	srv := newTestServer(t, testMaster, testVoltageCurrent, testIsolator)
+	srv.Handle(testVoltageCurrent.UID, uint8(voltage_current_v2_bricklet.FunctionGetConfiguration), func([]byte) []byte {
+		return fakebrickd.Encode(voltage_current_v2_bricklet.Averaging16, voltage_current_v2_bricklet.ConversionTime1_1ms,
+			voltage_current_v2_bricklet.ConversionTime588us)
+	})
+	srv.Handle(testVoltageCurrent.UID, uint8(voltage_current_v2_bricklet.FunctionGetCalibration), func([]byte) []byte {
+		return fakebrickd.Encode(uint16(1), uint16(1), uint16(1), uint16(1))
+	})
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testVoltageCurrent.UID)
+	waitRegistered(t, b, testIsolator.UID)
+
+	settings := map[string]VoltageCurrentSettings{
+		"vc2": {Averaging: 256, Calibration: &VoltageCurrentCalibration{1, 1, 1000, 1023}},
+	}
+	if err := settings["vc2"].Validate(); err != nil {
+		t.Fatal(err)
+	}
+	b.Reload(Settings{VoltageCurrent: settings})
+	waitRegistered(t, b, testVoltageCurrent.UID)
+	req, err := srv.WaitForRequest(testVoltageCurrent.UID, uint8(voltage_current_v2_bricklet.FunctionSetConfiguration), waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fakebrickd.Encode(voltage_current_v2_bricklet.Averaging256, voltage_current_v2_bricklet.ConversionTime1_1ms,
+		voltage_current_v2_bricklet.ConversionTime588us); string(req.Payload) != string(want) {
+		t.Errorf("configuration = %v, want %v", req.Payload, want)
+	}
+	req, err = srv.WaitForRequest(testVoltageCurrent.UID, uint8(voltage_current_v2_bricklet.FunctionSetCalibration), waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fakebrickd.Encode(uint16(1), uint16(1), uint16(1000), uint16(1023)); string(req.Payload) != string(want) {
+		t.Errorf("calibration = %v, want %v", req.Payload, want)
+	}
+
+	srv.Callback(testVoltageCurrent.UID, uint8(voltage_current_v2_bricklet.FunctionCallbackVoltage), int32(13250))
+	srv.Callback(testVoltageCurrent.UID, uint8(voltage_current_v2_bricklet.FunctionCallbackCurrent), int32(-1500))
+	srv.Callback(testVoltageCurrent.UID, uint8(voltage_current_v2_bricklet.FunctionCallbackPower), int32(19875))
+	connected := [8]byte{'v', 'c', '2'}
+	srv.Callback(testIsolator.UID, uint8(isolator_bricklet.FunctionCallbackStatistics), uint32(120), uint32(118),
+		uint16(voltage_current_v2_bricklet.DeviceIdentifier), connected)
+	for _, name := range []string{"voltage", "current", "power"} {
+		waitValue(t, b, testVoltageCurrent.UID, name)
+	}
+	waitValue(t, b, testIsolator.UID, "isolator_connected_bricklet")
+
+	metrics := gather(t, b)
+	for _, tc := range []struct {
+		name   string
+		labels map[string]string
+		value  float64
+	}{
+		{"brickd_voltage_value", map[string]string{"uid": "vc2"}, 13.25},
+		{"brickd_current_value", map[string]string{"uid": "vc2"}, -1.5},
+		{"brickd_power_value", map[string]string{"uid": "vc2"}, 19.875},
+		{"brickd_isolator_messages_from_brick_total", map[string]string{"uid": "is1"}, 120},
+		{"brickd_isolator_messages_from_bricklet_total", map[string]string{"uid": "is1"}, 118},
+		{"brickd_isolator_connected_bricklet_value", map[string]string{"uid": "is1", "connected_uid": "vc2"}, voltage_current_v2_bricklet.DeviceIdentifier},
+	} {
+		if v := metricValue(findMetric(t, metrics, tc.name, tc.labels)); !approx(v, tc.value) {
+			t.Errorf("%s%v = %f, want %f", tc.name, tc.labels, v, tc.value)
+		}
+	}
+
+	if err := (VoltageCurrentSettings{Averaging: 3}).Validate(); err == nil {
+		t.Errorf("averaging of 3 accepted")
+	}
+}
+
+func TestLoadCell(t *testing.T) {
+	srv := newTestServer(t, testMaster, testLoadCell)
+	srv.Handle(testLoadCell.UID, uint8(load_cell_v2_bricklet.FunctionGetWeight), func([]byte) []byte {
+		return fakebrickd.Encode(int32(12500))
+	})
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testLoadCell.UID)
+
+	b.Reload(Settings{LoadCells: map[string]LoadCellSettings{"Lc2": {MovingAverage: 40, Tare: 1.5}}})
+	waitRegistered(t, b, testLoadCell.UID)
+	req, err := srv.WaitForRequest(testLoadCell.UID, uint8(load_cell_v2_bricklet.FunctionSetMovingAverage), waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(req.Payload) != string(fakebrickd.Encode(uint16(40))) {
+		t.Errorf("moving average = %v, want 40", req.Payload)
+	}
+
+	weight := func(raw int32, want float64) {
+		t.Helper()
+		srv.Callback(testLoadCell.UID, uint8(load_cell_v2_bricklet.FunctionCallbackWeight), raw)
+		waitValueEquals(t, b, testLoadCell.UID, "weight", want)
+	}
+	weight(12500, 11)
+
+	// the scale is kept when the device is enumerated again
+	b.RLock()
+	enumerated := b.Data.Devices[testLoadCell.UID]
+	b.RUnlock()
+	b.Connection.Enumerate()
+	waitFor(t, "enumeration of "+testLoadCell.UID, func() bool {
+		b.RLock()
+		defer b.RUnlock()
+		return b.Data.Devices[testLoadCell.UID] != enumerated
+	})
+
+	saved := make(map[string]float64)
+	b.SetTareSaver(func(uid string, tare float64) error {
+		saved[uid] = tare
+		return nil
+	})
+	mc, err := NewMultiCollector(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	mc.APIHandler(rec, httptest.NewRequest(http.MethodPost, "/api/devices/Lc2/tare", nil))
+	if rec.Code != http.StatusForbidden || len(saved) != 0 {
+		t.Errorf("tare without admin API = %d %s, want 403", rec.Code, rec.Body)
+	}
+	mc.AdminAPI = true
+	rec = httptest.NewRecorder()
+	mc.APIHandler(rec, httptest.NewRequest(http.MethodPost, "/api/devices/Lc2/tare", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"weight":12.5`) {
+		t.Errorf("tare = %d %s, want 200 with a weight of 12.5", rec.Code, rec.Body)
+	}
+	if saved["Lc2"] != 12.5 {
+		t.Errorf("saved tare = %g, want 12.5", saved["Lc2"])
+	}
+	weight(13000, 0.5)
+
+	rec = httptest.NewRecorder()
+	mc.APIHandler(rec, httptest.NewRequest(http.MethodPost, "/api/devices/Lc2/calibrate", strings.NewReader(`{"weight": 2.25}`)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("calibrate = %d %s, want 200", rec.Code, rec.Body)
+	}
+	req, err = srv.WaitForRequest(testLoadCell.UID, uint8(load_cell_v2_bricklet.FunctionCalibrate), waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(req.Payload) != string(fakebrickd.Encode(uint32(2250))) {
+		t.Errorf("calibration weight = %v, want 2250 g", req.Payload)
+	}
+
+	rec = httptest.NewRecorder()
+	mc.APIHandler(rec, httptest.NewRequest(http.MethodPost, "/api/devices/6qb/tare", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("tare of the master brick = %d, want 400", rec.Code)
+	}
+
+	// the config with the saved tare is reloaded
+	b.Reload(Settings{LoadCells: map[string]LoadCellSettings{"Lc2": {MovingAverage: 40, Tare: 12.5}}})
+	weight(13000, 0.5)
+
+	// a tare which can not be saved is not used
+	b.SetTareSaver(func(string, float64) error { return errors.New("read-only file system") })
+	srv.Handle(testLoadCell.UID, uint8(load_cell_v2_bricklet.FunctionGetWeight), func([]byte) []byte {
+		return fakebrickd.Encode(int32(12000))
+	})
+	rec = httptest.NewRecorder()
+	mc.APIHandler(rec, httptest.NewRequest(http.MethodPost, "/api/devices/Lc2/tare", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("tare on a read-only file system = %d, want 500", rec.Code)
+	}
+	weight(14000, 1.5)
+}
+
+func TestEvents(t *testing.T) {
+	srv := newTestServer(t, testMaster, testMotionDetector, testHallEffect, testTilt)
+	srv.Handle(testHallEffect.UID, uint8(hall_effect_v2_bricklet.FunctionGetCounterConfig), func([]byte) []byte {
+		return fakebrickd.Encode(int16(1000), int16(-1000), uint32(100000))
+	})
+	srv.Handle(testMotionDetector.UID, uint8(motion_detector_v2_bricklet.FunctionGetMotionDetected), func([]byte) []byte {
+		return fakebrickd.Encode(uint8(0))
+	})
+	srv.Handle(testTilt.UID, uint8(tilt_bricklet.FunctionGetTiltState), func([]byte) []byte {
+		return fakebrickd.Encode(tilt_bricklet.TiltStateOpen)
+	})
+	expire := 500 * time.Millisecond
+	b := newTestCollector(t, srv, "", expire, nil)
+	for _, uid := range []string{testMotionDetector.UID, testHallEffect.UID, testTilt.UID} {
+		waitRegistered(t, b, uid)
+	}
+
+	// the flux density is read every callback period of 100ms, which is shorter than 200ms
+	req, err := srv.WaitForRequest(testHallEffect.UID, uint8(hall_effect_v2_bricklet.FunctionSetMagneticFluxDensityCallbackConfiguration), waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if period := binary.LittleEndian.Uint32(req.Payload); period != 100 {
+		t.Errorf("magnetic flux density period = %d ms, want 100 ms", period)
+	}
+
+	srv.Callback(testMotionDetector.UID, uint8(motion_detector_v2_bricklet.FunctionCallbackMotionDetected))
+	srv.Callback(testMotionDetector.UID, uint8(motion_detector_v2_bricklet.FunctionCallbackMotionDetected))
+	waitValueEquals(t, b, testMotionDetector.UID, "motion_events", 2)
+	srv.Callback(testMotionDetector.UID, uint8(motion_detector_v2_bricklet.FunctionCallbackDetectionCycleEnded))
+	waitValueEquals(t, b, testMotionDetector.UID, "motion", 0)
+
+	// the door is closed, opened and closed again
+	for _, density := range []int16{1500, 1400, 200, -1200} {
+		srv.Callback(testHallEffect.UID, uint8(hall_effect_v2_bricklet.FunctionCallbackMagneticFluxDensity), density)
+		waitValueEquals(t, b, testHallEffect.UID, "magnetic_flux_density", float64(density))
+	}
+	waitValueEquals(t, b, testHallEffect.UID, "door_events", 2)
+	waitValueEquals(t, b, testHallEffect.UID, "door", 0)
+
+	srv.Callback(testTilt.UID, uint8(tilt_bricklet.FunctionCallbackTiltState), tilt_bricklet.TiltStateOpen)
+	waitValueEquals(t, b, testTilt.UID, "tilt_events", 1)
+	waitValueEquals(t, b, testTilt.UID, "tilt", 1)
+
+	metrics := gather(t, b)
+	for _, tc := range []struct {
+		name  string
+		uid   string
+		value float64
+	}{
+		{"brickd_motion_value", "md2", 0},
+		{"brickd_motion_events_total", "md2", 2},
+		{"brickd_door_value", "he2", 0},
+		{"brickd_door_events_total", "he2", 2},
+		{"brickd_tilt_value", "ti1", 1},
+		{"brickd_tilt_vibration_value", "ti1", 0},
+		{"brickd_tilt_events_total", "ti1", 1},
+	} {
+		if v := metricValue(findMetric(t, metrics, tc.name, map[string]string{"uid": tc.uid})); v != tc.value {
+			t.Errorf("%s of %s = %f, want %f", tc.name, tc.uid, v, tc.value)
+		}
+	}
+
+	// the message published on an event only contains the values of the sensor
+	b.RLock()
+	msgs := b.valueMessages(testMotionDetector.UID, 0)
+	b.RUnlock()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages for the motion detector, want 1", len(msgs))
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(msgs[0].Payload, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data["motion"] != 0.0 || data["motion_events"] != 2.0 {
+		t.Errorf("motion detector message = %s", msgs[0].Payload)
+	}
+
+	// the states and event counters do not expire without events, other values do
+	time.Sleep(3 * expire)
+	metrics = gather(t, b)
+	for _, tc := range []struct {
+		name  string
+		uid   string
+		value float64
+	}{
+		{"brickd_motion_value", "md2", 0},
+		{"brickd_motion_events_total", "md2", 2},
+		{"brickd_door_value", "he2", 0},
+		{"brickd_door_events_total", "he2", 2},
+		{"brickd_tilt_value", "ti1", 1},
+		{"brickd_tilt_events_total", "ti1", 1},
+	} {
+		if v := metricValue(findMetric(t, metrics, tc.name, map[string]string{"uid": tc.uid})); v != tc.value {
+			t.Errorf("%s of %s after %s = %f, want %f", tc.name, tc.uid, 3*expire, v, tc.value)
+		}
+	}
+	if len(metrics["brickd_magnetic_flux_density_value"]) != 0 {
+		t.Errorf("magnetic flux density not expired")
+	}
+	srv.Callback(testMotionDetector.UID, uint8(motion_detector_v2_bricklet.FunctionCallbackMotionDetected))
+	waitValueEquals(t, b, testMotionDetector.UID, "motion_events", 3)
+}
+
+func TestVibration(t *testing.T) {
+	srv := newTestServer(t, testMaster, testIMU, testAccelerometer)
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testIMU.UID)
+	waitRegistered(t, b, testAccelerometer.UID)
+
+	settings := map[string]VibrationSettings{"im3": {SampleRate: 50}, "ac2": {SampleRate: 1000, FullScale: 4}}
+	b.Reload(Settings{Vibration: settings})
+	waitRegistered(t, b, testIMU.UID)
+	waitRegistered(t, b, testAccelerometer.UID)
+	req, err := srv.WaitForRequest(testIMU.UID, uint8(imu_v3_bricklet.FunctionSetLinearAccelerationCallbackConfiguration), waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fakebrickd.Encode(uint32(20), false); string(req.Payload) != string(want) {
+		t.Errorf("linear acceleration configuration = %v, want %v", req.Payload, want)
+	}
+	req, err = srv.WaitForRequest(testAccelerometer.UID, uint8(accelerometer_v2_bricklet.FunctionSetConfiguration), waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fakebrickd.Encode(accelerometer_v2_bricklet.DataRate1600Hz, accelerometer_v2_bricklet.FullScale4g); string(req.Payload) != string(want) {
+		t.Errorf("accelerometer configuration = %v, want %v", req.Payload, want)
+	}
+	if _, err := srv.WaitForRequest(testAccelerometer.UID, uint8(accelerometer_v2_bricklet.FunctionSetContinuousAccelerationConfiguration), waitTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Callback(testIMU.UID, uint8(imu_v3_bricklet.FunctionCallbackAllData), [3]int16{}, [3]int16{}, [3]int16{},
+		[3]int16{160, -32, 48}, [4]int16{}, [3]int16{100, 0, -50}, [3]int16{}, int8(25), uint8(0xe4))
+	waitValueEquals(t, b, testIMU.UID, "imu_calibration_system", 3)
+
+	// x alternates between ±1024, which is ±0.125gₙ at 4gₙ full scale, z is 0.25gₙ
+	var samples [30]int16
+	for i := 0; i < len(samples); i += 3 {
+		samples[i] = 1024
+		if i%2 == 1 {
+			samples[i] = -1024
+		}
+		samples[i+2] = 2048
+	}
+	srv.Callback(testAccelerometer.UID, uint8(accelerometer_v2_bricklet.FunctionCallbackContinuousAcceleration16Bit), samples)
+	waitValueEquals(t, b, testAccelerometer.UID, "vibration_crest_factor", 1)
+
+	// the samples of the IMU arrive one by one, so the summary is checked once a period has
+	// an even number of them
+	waitFor(t, "vibration peak of im3", func() bool {
+		srv.Callback(testIMU.UID, uint8(imu_v3_bricklet.FunctionCallbackLinearAcceleration), int16(50), int16(0), int16(0))
+		srv.Callback(testIMU.UID, uint8(imu_v3_bricklet.FunctionCallbackLinearAcceleration), int16(-50), int16(0), int16(0))
+		b.RLock()
+		defer b.RUnlock()
+		for _, v := range b.Data.Values[testIMU.UID] {
+			if v.Name == "vibration_rms" && v.Labels["axis"] == "x" && approx(v.Value, 0.5) {
+				return true
+			}
+		}
+		return false
+	})
+
+	metrics := gather(t, b)
+	for _, tc := range []struct {
+		name   string
+		labels map[string]string
+		value  float64
+	}{
+		{"brickd_heading_value", map[string]string{"uid": "im3"}, 10},
+		{"brickd_roll_value", map[string]string{"uid": "im3"}, -2},
+		{"brickd_pitch_value", map[string]string{"uid": "im3"}, 3},
+		{"brickd_linear_acceleration_value", map[string]string{"uid": "im3", "axis": "z"}, -0.5},
+		{"brickd_imu_temperature_value", map[string]string{"uid": "im3"}, 25},
+		{"brickd_imu_calibration_magnetometer_value", map[string]string{"uid": "im3"}, 0},
+		{"brickd_imu_calibration_accelerometer_value", map[string]string{"uid": "im3"}, 1},
+		{"brickd_imu_calibration_gyroscope_value", map[string]string{"uid": "im3"}, 2},
+		{"brickd_vibration_rms_value", map[string]string{"uid": "ac2", "axis": "x"}, 0.125 * standardGravity},
+		{"brickd_vibration_peak_value", map[string]string{"uid": "ac2", "axis": "x"}, 0.125 * standardGravity},
+		{"brickd_vibration_rms_value", map[string]string{"uid": "ac2", "axis": "z"}, 0},
+		{"brickd_acceleration_value", map[string]string{"uid": "ac2", "axis": "x"}, 0},
+		{"brickd_acceleration_value", map[string]string{"uid": "ac2", "axis": "z"}, 0.25 * standardGravity},
+	} {
+		if v := metricValue(findMetric(t, metrics, tc.name, tc.labels)); !approx(v, tc.value) {
+			t.Errorf("%s%v = %f, want %f", tc.name, tc.labels, v, tc.value)
+		}
+	}
+
+	if err := (VibrationSettings{FullScale: 16}).Validate(); err == nil {
+		t.Errorf("full scale of 16 accepted")
+	}
+	if err := (VibrationSettings{SampleRate: 12800}).Validate(); err == nil {
+		t.Errorf("sample rate of 12800 accepted")
+	}
+
+	// the IMU samples at most at the 100 Hz of the sensor fusion
+	b.Reload(Settings{Vibration: map[string]VibrationSettings{"im3": {SampleRate: 1000}}})
+	waitFor(t, "linear acceleration period of 10 ms", func() bool {
+		want := string(fakebrickd.Encode(uint32(10), false))
+		for _, req := range srv.Requests(testIMU.UID, uint8(imu_v3_bricklet.FunctionSetLinearAccelerationCallbackConfiguration)) {
+			if string(req.Payload) == want {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func TestExpire(t *testing.T) {
+	srv := newTestServer(t, testHumidity)
+	b := newTestCollector(t, srv, "", 300*time.Millisecond, nil)
+	waitRegistered(t, b, testHumidity.UID)
+
+	srv.Callback(testHumidity.UID, uint8(humidity_v2_bricklet.FunctionCallbackHumidity), uint16(4512))
+	waitValue(t, b, testHumidity.UID, "humidity")
+	waitFor(t, "expiry of humidity", func() bool {
+		return len(gather(t, b)["brickd_humidity_value"]) == 0
+	})
+}
+
+func TestDisconnect(t *testing.T) {
+	srv := newTestServer(t, testMaster, testHumidity)
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testHumidity.UID)
+
+	srv.DropConnections()
+	waitFor(t, "disconnect", func() bool {
+		b.RLock()
+		defer b.RUnlock()
+		var disconnects int64
+		for _, count := range b.DisconnectCounter {
+			disconnects += count
+		}
+		return disconnects == 1
+	})
+
+	// auto reconnect enumerates and registers the devices again
+	waitFor(t, "reconnect", func() bool {
+		b.RLock()
+		defer b.RUnlock()
+		return b.ConnectCounter == 2
+	})
+	waitRegistered(t, b, testHumidity.UID)
+	srv.Callback(testHumidity.UID, uint8(humidity_v2_bricklet.FunctionCallbackHumidity), uint16(5000))
+	if v := waitValue(t, b, testHumidity.UID, "humidity"); v.Value != 50 {
+		t.Errorf("humidity after reconnect = %f, want 50", v.Value)
+	}
+}
+
+func TestRemoveDevice(t *testing.T) {
+	srv := newTestServer(t, testMaster, testHumidity)
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testHumidity.UID)
+
+	srv.RemoveDevice(testHumidity.UID)
+	waitFor(t, "removal of "+testHumidity.UID, func() bool {
+		b.RLock()
+		defer b.RUnlock()
+		_, known := b.Data.Devices[testHumidity.UID]
+		_, registered := b.Registry[testHumidity.UID]
+		return !known && !registered
+	})
+}
+
+func TestAuthentication(t *testing.T) {
+	srv := newTestServer(t, testHumidity)
+	srv.Secret = "secret"
+
+	wrong := newTestCollector(t, srv, "wrong", 0, nil)
+	waitFor(t, "authentication failure", func() bool {
+		wrong.RLock()
+		defer wrong.RUnlock()
+		return wrong.AuthFailureCounter == 1
+	})
+
+	b := newTestCollector(t, srv, "secret", 0, nil)
+	waitRegistered(t, b, testHumidity.UID)
+
+	wrong.RLock()
+	defer wrong.RUnlock()
+	if wrong.ConnectCounter != 0 || len(wrong.Data.Devices) != 0 {
+		t.Errorf("collector with wrong password has %d connections and %d devices", wrong.ConnectCounter, len(wrong.Data.Devices))
+	}
+}
+
+func TestMQTTMessages(t *testing.T) {
+	srv := newTestServer(t, testMaster, testHumidity)
+	b := newTestCollector(t, srv, "", 0, map[string]map[string]map[string]string{
+		"hum": {"0": {"mqtt_topic": "living_room", "room": "living room"}},
+	})
+	waitRegistered(t, b, testMaster.UID)
+	waitRegistered(t, b, testHumidity.UID)
+
+	srv.Callback(testHumidity.UID, uint8(humidity_v2_bricklet.FunctionCallbackHumidity), uint16(4512))
+	srv.Callback(testMaster.UID, uint8(master_brick.FunctionCallbackStackVoltage), uint16(5012))
+	waitValue(t, b, testHumidity.UID, "humidity")
+	waitValue(t, b, testMaster.UID, "stack_voltage")
+
+	payloads := make(map[string]map[string]interface{})
+	for _, msg := range b.mqttMessages() {
+		var p map[string]interface{}
+		if err := json.Unmarshal(msg.Payload, &p); err != nil {
+			t.Fatalf("invalid payload for %s: %s", msg.Topic, err)
+		}
+		payloads[msg.Topic] = p
+	}
+
+	if up := payloads["brickd_exporter"]["up"]; up != 1.0 {
+		t.Errorf("brickd_exporter up = %v, want 1", up)
+	}
+	room := payloads["living_room"]
+	if h, _ := room["humidity"].(float64); !approx(h, 45.12) {
+		t.Errorf("living_room humidity = %v, want 45.12", room["humidity"])
+	}
+	if labels, _ := room["labels"].(map[string]interface{}); labels["room"] != "living room" || labels["site"] != "test" {
+		t.Errorf("living_room labels = %v", room["labels"])
+	}
+	if _, ok := room["labels"].(map[string]interface{})["mqtt_topic"]; ok {
+		t.Errorf("mqtt_topic in labels of living_room")
+	}
+	if v, _ := payloads["master_brick"]["stack_voltage"].(float64); !approx(v, 5.012) {
+		t.Errorf("master_brick stack_voltage = %v, want 5.012", v)
+	}
+}
+
+func TestHAStateTopics(t *testing.T) {
+	srv := newTestServer(t, testMaster, testQuadRelay, testGPS)
+	srv.Respond(testQuadRelay.UID, uint8(industrial_quad_relay_v2_bricklet.FunctionGetValue), uint8(0x05)) // channels 0 and 2 on
+	srv.Respond(testGPS.UID, uint8(gps_v2_bricklet.FunctionGetStatus), true, uint8(9))
+	srv.Respond(testGPS.UID, uint8(gps_v2_bricklet.FunctionGetCoordinates), uint32(52520008), uint8('N'), uint32(13404954), uint8('E'))
+	b := newTestCollector(t, srv, "", 0, map[string]map[string]map[string]string{
+		"qr1": {"1": {"mqtt_topic": "ventilation"}},
+	})
+	b.Lock()
+	b.MQTT = &mqtt.MQTT{Topic: "brickd"}
+	b.Unlock()
+	waitRegistered(t, b, testQuadRelay.UID)
+	waitRegistered(t, b, testGPS.UID)
+	waitFor(t, "output states", func() bool {
+		return len(gather(t, b)["brickd_output_value"]) == 4
+	})
+	waitValue(t, b, testGPS.UID, "latitude")
+
+	published := make(map[string]map[string]interface{})
+	for _, msg := range b.mqttMessages() {
+		var p map[string]interface{}
+		if err := json.Unmarshal(msg.Payload, &p); err != nil {
+			t.Fatalf("invalid payload for %s: %s", msg.Topic, err)
+		}
+		published[b.MQTT.Topic.Name(msg.Topic)] = p
+	}
+
+	// the state topic in the HA config is the topic the state of the channel is published on
+	b.RLock()
+	relay, gps := b.Data.Devices[testQuadRelay.UID], b.Data.Devices[testGPS.UID]
+	b.RUnlock()
+	for ch, want := range []float64{1, 0, 1, 0} {
+		cfg := b.haConfig("switch", "", outputState.Name, "", "", "qr1", relay, int64(ch), strconv.Itoa(ch))
+		p, ok := published[cfg.StateTopic]
+		if !ok {
+			t.Errorf("nothing published on state topic %s of channel %d", cfg.StateTopic, ch)
+			continue
+		}
+		if p["output"] != want || p["labels"].(map[string]interface{})["sensor_id"] != strconv.Itoa(ch) {
+			t.Errorf("state topic %s of channel %d = %v, want output %f", cfg.StateTopic, ch, p, want)
+		}
+	}
+	if cfg := b.haConfig("switch", "", outputState.Name, "", "", "qr1", relay, 1, "1"); cfg.StateTopic != "brickd/ventilation" {
+		t.Errorf("state topic of channel 1 = %s, want brickd/ventilation", cfg.StateTopic)
+	}
+	cfg := b.haConfig("device_tracker", "", gpsV2Latitude.Name, "", "", "gp2", gps, 0, "")
+	if _, ok := published[cfg.JSONAttributesTopic]["latitude"]; !ok {
+		t.Errorf("no latitude published on the attributes topic %s of the GPS", cfg.JSONAttributesTopic)
+	}
+}
+
+func TestReload(t *testing.T) {
+	srv := newTestServer(t, testMaster, testHumidity)
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testMaster.UID)
+	waitRegistered(t, b, testHumidity.UID)
+	srv.Callback(testMaster.UID, uint8(master_brick.FunctionCallbackStackVoltage), uint16(5012))
+	waitValue(t, b, testMaster.UID, "stack_voltage")
+
+	b.Reload(Settings{
+		IgnoredUIDs: []string{testHumidity.UID},
+		Labels:      map[string]string{"site": "reloaded", "room": "cellar"},
+		LEDStatus:   "off",
+	})
+	b.RLock()
+	_, known := b.Data.Devices[testHumidity.UID]
+	b.RUnlock()
+	if known {
+		t.Errorf("ignored device %s not removed", testHumidity.UID)
+	}
+	findMetric(t, gather(t, b), "brickd_stack_voltage_value", map[string]string{"uid": "6qb", "site": "reloaded", "room": "cellar"})
+
+	// no longer ignored devices are registered again
+	b.Reload(Settings{LEDStatus: "off"})
+	waitRegistered(t, b, testHumidity.UID)
+	req, err := srv.WaitForRequest(testHumidity.UID, uint8(humidity_v2_bricklet.FunctionSetStatusLEDConfig), waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Payload[0] != StatusLEDConfigs["off"] {
+		t.Errorf("status LED of %s set to %d, want off", testHumidity.UID, req.Payload[0])
+	}
+}
+
+func TestClose(t *testing.T) {
+	srv := newTestServer(t, testHumidity)
+	b := newTestCollector(t, srv, "", 0, nil)
+	waitRegistered(t, b, testHumidity.UID)
+
+	b.Close()
+	waitFor(t, "disconnect from brickd", func() bool {
+		return srv.Connections() == 0
+	})
+}
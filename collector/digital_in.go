@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// the values of the digital input bricklets, the sensor id is the channel. The Index of
+// the edge count is the number of channels of the bricklet, so the values of all channels
+// have unique indexes.
+
+// digitalInputLevel returns the level value of a bricklet
+func digitalInputLevel() ValueDesc {
+	return ValueDesc{
+		Index:  0,
+		Name:   "digital_input",
+		Help:   "Level of the digital input channel, high (1) or low (0)",
+		Type:   prometheus.GaugeValue,
+		HAType: "binary_sensor",
+	}
+}
+
+// digitalInputEdges returns the edge count value of a bricklet with the given number of
+// channels
+func digitalInputEdges(channels int) ValueDesc {
+	return ValueDesc{
+		Index:      channels,
+		Name:       "digital_input_edges",
+		Help:       "Number of edges counted on the digital input channel",
+		Type:       prometheus.CounterValue,
+		HAType:     "sensor",
+		StateClass: "total_increasing",
+	}
+}
+
+// inputChannels returns the channels of an IO bricklet configured as input. The bindings
+// fail to decode the direction returned by GetConfiguration (a single char is read as a 4
+// byte rune), so channels with an unknown direction are probed by reading their edge
+// counter, which is only allowed for inputs.
+func inputChannels(channels uint8, direction func(channel uint8) (rune, error),
+	edgeCount func(channel uint8) (uint32, error)) []uint8 {
+	var inputs []uint8
+	for ch := uint8(0); ch < channels; ch++ {
+		switch dir, _ := direction(ch); dir {
+		case 'i':
+			inputs = append(inputs, ch)
+		case 'o':
+		default:
+			if _, err := edgeCount(ch); err == nil {
+				inputs = append(inputs, ch)
+			}
+		}
+	}
+	return inputs
+}
+
+// sendLevels sends the levels of the given channels
+func (b *BrickdCollector) sendLevels(dev *Device, desc ValueDesc, channels []uint8, values []bool) {
+	for _, ch := range channels {
+		b.Send(dev, int64(ch), desc, bool2Float(values[ch]))
+	}
+}
+
+// PollEdgeCounts reads the edge counters of the channels every callback period, the returned
+// Register stops it. The counters are read without resetting them, a count lower than the
+// previous one means the bricklet was reset. The exported counter keeps increasing in this
+// case.
+func (b *BrickdCollector) PollEdgeCounts(dev *Device, desc ValueDesc, channels []uint8,
+	edgeCount func(channel uint8) (uint32, error)) Register {
+	last := make(map[uint8]uint32)
+	total := make(map[uint8]float64)
+	return b.poll(make(chan struct{}), func() {
+		for _, ch := range channels {
+			count, err := edgeCount(ch)
+			if err != nil {
+				log.Infof("failed to get edge count of channel %d (uid=%s): %s", ch, dev.UID, err)
+				continue
+			}
+			prev, ok := last[ch]
+			switch {
+			case !ok:
+				total[ch] = float64(count)
+			case count < prev:
+				log.Debugf("edge count of channel %d reset (uid=%s)", ch, dev.UID)
+				total[ch] += float64(count)
+			default:
+				total[ch] += float64(count - prev)
+			}
+			last[ch] = count
+			b.Send(dev, int64(ch), desc, total[ch])
+		}
+	})
+}
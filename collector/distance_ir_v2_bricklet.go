@@ -0,0 +1,45 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/distance_ir_v2_bricklet"
+)
+
+func init() {
+	RegisterDriver(distanceIRV2Bricklet{})
+}
+
+type distanceIRV2Bricklet struct{}
+
+func (distanceIRV2Bricklet) DeviceIdentifier() uint16 {
+	return distance_ir_v2_bricklet.DeviceIdentifier
+}
+func (distanceIRV2Bricklet) Name() string { return "distance_ir_bricklet_v2" }
+
+func (distanceIRV2Bricklet) Values() []ValueDesc { return distanceValues() }
+
+func (distanceIRV2Bricklet) HAValues(b *BrickdCollector, dev *Device) []ValueDesc {
+	return b.tankValues(dev)
+}
+
+func (distanceIRV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := distance_ir_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Distance IR Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	distID := d.RegisterDistanceCallback(func(distance uint16) {
+		b.sendDistance(dev, float64(distance)/1000) // mm
+	})
+	d.SetDistanceCallbackConfiguration(b.CallbackPeriod, false, 'x', 0, 0)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterDistanceCallback,
+			ID:         distID,
+		},
+	}, nil
+}
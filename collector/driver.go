@@ -0,0 +1,245 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Tinkerforge/go-api-bindings/ipconnection"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Driver is implemented by all supported bricks and bricklets. Drivers register themselves
+// with RegisterDriver in an init() function, so adding a new device is done by adding a
+// single file with its driver, see humidity_v2_bricklet.go for an example.
+type Driver interface {
+	// DeviceIdentifier is the Tinkerforge device identifier, see
+	// https://www.tinkerforge.com/en/doc/Software/Device_Identifier.html
+	DeviceIdentifier() uint16
+	// Name is used in the HomeAssistant unique IDs, e.g. "humidity_bricklet_v2"
+	Name() string
+	// Values describes all values sent by the device
+	Values() []ValueDesc
+	// Register connects to the device, configures it and registers the callbacks. The
+	// returned registrations are deregistered when the device is gone. It is called without
+	// the collector being locked.
+	Register(b *BrickdCollector, dev *Device) ([]Register, error)
+}
+
+// MultiSensorDriver is implemented by drivers of devices with several sensors, such as the
+// Outdoor Weather Bricklet. The HomeAssistant config is not published for their Values()
+// after registration, they have to publish it for each sensor with PublishHAConfig.
+type MultiSensorDriver interface {
+	Driver
+	MultiSensor()
+}
+
+// haUniqueIDer may be implemented by drivers which need a different HomeAssistant unique
+// ID than "<Name>_<uid>", e.g. to keep the entities of older versions
+type haUniqueIDer interface {
+	HAUniqueID(uid string) string
+}
+
+// haValuer may be implemented by drivers which publish the HomeAssistant config only for some
+// of their Values(), e.g. depending on the configuration of the device
+type haValuer interface {
+	HAValues(b *BrickdCollector, dev *Device) []ValueDesc
+}
+
+// ValueDesc describes a value sent by a device. The prometheus metric, the MQTT payload and
+// the HomeAssistant config are all derived from it.
+type ValueDesc struct {
+	Index       int                  // index in BrickData.Values, unique per device (and sensor)
+	Name        string               // value name, such as "usb_voltage" or "humidity"
+	Help        string               // prometheus help text, must be the same for all values with this name
+	Type        prometheus.ValueType // prometheus.GaugeValue or prometheus.CounterValue
+	Unit        string               // unit of the scaled value, e.g. "°C"
+	Scale       float64              // the raw value from the device is multiplied by Scale, 0 is the same as 1
+	HAType      string               // HomeAssistant type, "sensor" or "binary_sensor", empty to not publish a config
+	DeviceClass string               // HomeAssistant device class
+	StateClass  string               // HomeAssistant state class, e.g. "total_increasing" for energy counters
+	MetricName  string               // full prometheus metric name, defaults to "brickd_<Name>_value" or "brickd_<Name>_total"
+	Labels      []string             // names of the additional labels sent with SendLabels, e.g. "weighting"
+}
+
+// Metric returns the prometheus metric of the value
+func (v ValueDesc) Metric() Metric {
+	return Metric{
+		Name:     v.Name,
+		Help:     v.Help,
+		Type:     v.Type,
+		FullName: v.MetricName,
+	}
+}
+
+// scaled returns the raw value from the device in the unit of the value
+func (v ValueDesc) scaled(raw float64) float64 {
+	if v.Scale == 0 {
+		return raw
+	}
+	return raw * v.Scale
+}
+
+var drivers = make(map[uint16]Driver)
+
+// RegisterDriver makes the driver available to all collectors, it panics when a driver for
+// the same device identifier is already registered
+func RegisterDriver(drv Driver) {
+	id := drv.DeviceIdentifier()
+	if _, exists := drivers[id]; exists {
+		panic(fmt.Sprintf("driver for %s (%d) registered twice", DeviceName(id), id))
+	}
+	drivers[id] = drv
+}
+
+// Drivers returns all registered drivers by device identifier
+func Drivers() map[uint16]Driver {
+	d := make(map[uint16]Driver, len(drivers))
+	for id, drv := range drivers {
+		d[id] = drv
+	}
+	return d
+}
+
+// Metrics returns the metrics of all values of all registered drivers, sorted by device
+// identifier
+func Metrics() []Metric {
+	var ids []int
+	for id := range drivers {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	var metrics []Metric
+	for _, id := range ids {
+		for _, v := range drivers[uint16(id)].Values() {
+			metrics = append(metrics, v.Metric())
+		}
+	}
+	return metrics
+}
+
+// valueLabelNames returns the names of the additional labels of all values of all
+// registered drivers
+func valueLabelNames() []string {
+	var names []string
+	for _, drv := range drivers {
+		for _, v := range drv.Values() {
+			names = append(names, v.Labels...)
+		}
+	}
+	return names
+}
+
+// Send sends a raw value as received from the device to the collector, sensorID is 0
+// unless the device has several sensors
+func (b *BrickdCollector) Send(dev *Device, sensorID int64, desc ValueDesc, raw float64) {
+	b.SendLabels(dev, sensorID, desc, raw, nil)
+}
+
+// SendLabels sends a raw value like Send with additional labels, the label names must be
+// declared in desc.Labels
+func (b *BrickdCollector) SendLabels(dev *Device, sensorID int64, desc ValueDesc, raw float64, labels map[string]string) {
+	b.send(newValue(dev, sensorID, desc, raw, labels))
+}
+
+// newValue returns the value of a device with the raw value scaled
+func newValue(dev *Device, sensorID int64, desc ValueDesc, raw float64, labels map[string]string) Value {
+	return Value{
+		Index:    sensorID + int64(desc.Index),
+		DeviceID: dev.DeviceID,
+		UID:      dev.UID,
+		SensorID: sensorID,
+		Name:     desc.Name,
+		Value:    desc.scaled(raw),
+		Labels:   labels,
+	}
+}
+
+// send passes the value to the collector
+func (b *BrickdCollector) send(v Value) {
+	select {
+	case b.Values <- v:
+	case <-b.done:
+	}
+}
+
+// poll calls fn right away and then every callback period while connected to brickd, until
+// stop is closed or the collector is closed. The returned Register closes stop, so drivers
+// return it with their callbacks. Values which are only sent by callbacks on changes are
+// polled this way, so they don't expire.
+func (b *BrickdCollector) poll(stop chan struct{}, fn func()) Register {
+	period := time.Duration(b.CallbackPeriod) * time.Millisecond
+	go func() {
+		for {
+			if b.Connection.GetConnectionState() == ipconnection.ConnectionStateConnected {
+				fn()
+			}
+
+			select {
+			case <-stop:
+				return
+			case <-b.done:
+				return
+			case <-time.After(period):
+			}
+		}
+	}()
+	return Register{Deregister: func(uint64) { close(stop) }}
+}
+
+// haUniqueID returns the HomeAssistant unique ID of a device and the id of the HomeAssistant
+// device, which is only set for the sensors of MultiSensorDriver drivers
+func haUniqueID(drv Driver, dev *Device, sensorID int64) (uniqueID, deviceID string) {
+	uniqueID = drv.Name() + "_" + dev.UID
+	if u, ok := drv.(haUniqueIDer); ok {
+		uniqueID = u.HAUniqueID(dev.UID)
+	}
+	if _, ok := drv.(MultiSensorDriver); ok {
+		deviceID = strconv.FormatInt(sensorID, 10)
+		uniqueID += "_" + deviceID
+	}
+	return uniqueID, deviceID
+}
+
+// PublishHAConfig publishes the HomeAssistant config for the given values of a device,
+// the sensorID is only used by MultiSensorDriver drivers
+func (b *BrickdCollector) PublishHAConfig(drv Driver, dev *Device, sensorID int64, values []ValueDesc) {
+	uniqueID, deviceID := haUniqueID(drv, dev, sensorID)
+	for _, v := range values {
+		if v.HAType == "" {
+			continue
+		}
+		b.SetHAConfig(v.HAType, v.DeviceClass, v.Name, v.Unit, v.StateClass, uniqueID, dev, sensorID, deviceID)
+	}
+}
+
+// RemoveSensor removes the values of a sensor of a MultiSensorDriver device and the
+// HomeAssistant config of the given values, e.g. when the sensor has not been seen for a
+// long time
+func (b *BrickdCollector) RemoveSensor(drv MultiSensorDriver, dev *Device, sensorID int64, values []ValueDesc) {
+	b.Lock()
+	for i, v := range b.Data.Values[dev.UID] {
+		if v.SensorID == sensorID {
+			delete(b.Data.Values[dev.UID], i)
+		}
+	}
+	b.Unlock()
+
+	uniqueID, _ := haUniqueID(drv, dev, sensorID)
+	for _, v := range values {
+		if v.HAType == "" {
+			continue
+		}
+		b.RemoveHAConfig(v.HAType, v.Name, uniqueID)
+	}
+}
+
+// RemoveValues removes the given values of a device, e.g. when the device stopped measuring
+func (b *BrickdCollector) RemoveValues(dev *Device, sensorID int64, values []ValueDesc) {
+	b.Lock()
+	defer b.Unlock()
+	for _, v := range values {
+		delete(b.Data.Values[dev.UID], sensorID+int64(v.Index))
+	}
+}
@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/energy_monitor_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(energyMonitorBricklet{})
+}
+
+var (
+	energyMonitorVoltage = ValueDesc{
+		Index:       0,
+		Name:        "voltage",
+		Help:        "Voltage in V",
+		Type:        prometheus.GaugeValue,
+		Unit:        "V",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "voltage",
+	}
+	energyMonitorCurrent = ValueDesc{
+		Index:       1,
+		Name:        "current",
+		Help:        "Current in A",
+		Type:        prometheus.GaugeValue,
+		Unit:        "A",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "current",
+	}
+	energyMonitorEnergy = ValueDesc{
+		Index:       2,
+		Name:        "energy",
+		Help:        "Energy in Wh, reset when the bricklet is restarted",
+		Type:        prometheus.CounterValue,
+		Unit:        "Wh",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "energy",
+		StateClass:  "total_increasing",
+	}
+	energyMonitorRealPower = ValueDesc{
+		Index:       3,
+		Name:        "real_power",
+		Help:        "Real power in W",
+		Type:        prometheus.GaugeValue,
+		Unit:        "W",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "power",
+	}
+	energyMonitorApparentPower = ValueDesc{
+		Index:       4,
+		Name:        "apparent_power",
+		Help:        "Apparent power in VA",
+		Type:        prometheus.GaugeValue,
+		Unit:        "VA",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "apparent_power",
+	}
+	energyMonitorReactivePower = ValueDesc{
+		Index:       5,
+		Name:        "reactive_power",
+		Help:        "Reactive power in var",
+		Type:        prometheus.GaugeValue,
+		Unit:        "var",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "reactive_power",
+	}
+	energyMonitorPowerFactor = ValueDesc{
+		Index:       6,
+		Name:        "power_factor",
+		Help:        "Power factor between 0 and 1",
+		Type:        prometheus.GaugeValue,
+		Scale:       0.001,
+		HAType:      "sensor",
+		DeviceClass: "power_factor",
+	}
+	energyMonitorFrequency = ValueDesc{
+		Index:       7,
+		Name:        "frequency",
+		Help:        "AC frequency in Hz",
+		Type:        prometheus.GaugeValue,
+		Unit:        "Hz",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "frequency",
+	}
+)
+
+type energyMonitorBricklet struct{}
+
+func (energyMonitorBricklet) DeviceIdentifier() uint16 {
+	return energy_monitor_bricklet.DeviceIdentifier
+}
+func (energyMonitorBricklet) Name() string { return "energy_monitor_bricklet" }
+
+func (energyMonitorBricklet) Values() []ValueDesc {
+	return []ValueDesc{
+		energyMonitorVoltage,
+		energyMonitorCurrent,
+		energyMonitorEnergy,
+		energyMonitorRealPower,
+		energyMonitorApparentPower,
+		energyMonitorReactivePower,
+		energyMonitorPowerFactor,
+		energyMonitorFrequency,
+	}
+}
+
+func (energyMonitorBricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := energy_monitor_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Energy Monitor Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	dataID := d.RegisterEnergyDataCallback(func(voltage int32, current int32, energy int32, realPower int32,
+		apparentPower int32, reactivePower int32, powerFactor uint16, frequency uint16) {
+		b.Send(dev, 0, energyMonitorVoltage, float64(voltage))
+		b.Send(dev, 0, energyMonitorCurrent, float64(current))
+		b.Send(dev, 0, energyMonitorEnergy, float64(energy))
+		b.Send(dev, 0, energyMonitorRealPower, float64(realPower))
+		b.Send(dev, 0, energyMonitorApparentPower, float64(apparentPower))
+		b.Send(dev, 0, energyMonitorReactivePower, float64(reactivePower))
+		b.Send(dev, 0, energyMonitorPowerFactor, float64(powerFactor))
+		b.Send(dev, 0, energyMonitorFrequency, float64(frequency))
+	})
+	d.SetEnergyDataCallbackConfiguration(b.CallbackPeriod, false)
+
+	b.setStatusLED(dev, func(config uint8) error {
+		return d.SetStatusLEDConfig(energy_monitor_bricklet.StatusLEDConfig(config))
+	})
+
+	return []Register{
+		{
+			Deregister: d.DeregisterEnergyDataCallback,
+			ID:         dataID,
+		},
+	}, nil
+}
@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// the devices sending events instead of periodic values, e.g. the motion detectors, export
+// their current state and count the events. The MQTT message of the sensor is published on
+// every change of the state, not only every callback period. The event counters are kept
+// outside of the values so they are not reset when the values expire, and the state is sent
+// again every callback period with repeatEvents.
+
+// eventCount returns the event counter value of an event source, it is sent with SendEvent
+func eventCount(index int, name, help string) ValueDesc {
+	return ValueDesc{
+		Index:      index,
+		Name:       name,
+		Help:       help,
+		Type:       prometheus.CounterValue,
+		HAType:     "sensor",
+		StateClass: "total_increasing",
+	}
+}
+
+// SendState sends the state of an event source like Send and publishes the MQTT message of
+// the sensor right away
+func (b *BrickdCollector) SendState(dev *Device, sensorID int64, state ValueDesc, raw float64) {
+	v := newValue(dev, sensorID, state, raw, nil)
+	v.publish = true
+	b.send(v)
+}
+
+// SendEvent sends the new state of an event source like SendState and counts the event in
+// the events counter of the sensor
+func (b *BrickdCollector) SendEvent(dev *Device, sensorID int64, state ValueDesc, raw float64, events ValueDesc) {
+	b.Send(dev, sensorID, state, raw)
+	b.sendEvents(dev, sensorID, events, 1, true)
+}
+
+// sendEvents adds n events to the events counter of the sensor
+func (b *BrickdCollector) sendEvents(dev *Device, sensorID int64, events ValueDesc, n float64, publish bool) {
+	v := newValue(dev, sensorID, events, n, nil)
+	v.event = true
+	v.publish = publish
+	b.send(v)
+}
+
+// repeatEvents calls sendState and sends the events counter of the sensor every callback
+// period, so neither expires while there are no events. The returned Register stops it.
+func (b *BrickdCollector) repeatEvents(dev *Device, sensorID int64, events ValueDesc, sendState func()) Register {
+	return b.poll(make(chan struct{}), func() {
+		sendState()
+		b.sendEvents(dev, sensorID, events, 0, false)
+	})
+}
@@ -0,0 +1,272 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Tinkerforge/go-api-bindings/gps_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(gpsV2Bricklet{})
+}
+
+var (
+	gpsV2Latitude = ValueDesc{
+		Index:  0,
+		Name:   "latitude",
+		Help:   "Latitude in degrees, negative in the southern hemisphere",
+		Type:   prometheus.GaugeValue,
+		Unit:   "°",
+		Scale:  0.000001,
+		HAType: "device_tracker", // with the longitude, see setHAConfig
+	}
+	gpsV2Longitude = ValueDesc{
+		Index: 1,
+		Name:  "longitude",
+		Help:  "Longitude in degrees, negative west of the prime meridian",
+		Type:  prometheus.GaugeValue,
+		Unit:  "°",
+		Scale: 0.000001,
+	}
+	gpsV2Altitude = ValueDesc{
+		Index:       2,
+		Name:        "altitude",
+		Help:        "Altitude in m",
+		Type:        prometheus.GaugeValue,
+		Unit:        "m",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "distance",
+	}
+	gpsV2Speed = ValueDesc{
+		Index:       3,
+		Name:        "speed",
+		Help:        "Speed over ground in km/h",
+		Type:        prometheus.GaugeValue,
+		Unit:        "km/h",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "speed",
+	}
+	gpsV2Course = ValueDesc{
+		Index:  4,
+		Name:   "course",
+		Help:   "Course over ground in degrees, 0 is north",
+		Type:   prometheus.GaugeValue,
+		Unit:   "°",
+		Scale:  0.01,
+		HAType: "sensor",
+	}
+	gpsV2SatellitesInView = ValueDesc{
+		Index:  5,
+		Name:   "gps_satellites_in_view",
+		Help:   "Number of GPS satellites in view",
+		Type:   prometheus.GaugeValue,
+		HAType: "sensor",
+	}
+	gpsV2SatellitesUsed = ValueDesc{
+		Index:  6,
+		Name:   "gps_satellites_used",
+		Help:   "Number of GPS and GLONASS satellites used for the fix",
+		Type:   prometheus.GaugeValue,
+		HAType: "sensor",
+	}
+	gpsV2Fix = ValueDesc{
+		Index:  7,
+		Name:   "gps_fix",
+		Help:   "GPS fix, 1 for no fix, 2 for a 2D fix and 3 for a 3D fix",
+		Type:   prometheus.GaugeValue,
+		HAType: "sensor",
+	}
+	gpsV2HDOP = ValueDesc{
+		Index: 8,
+		Name:  "gps_hdop",
+		Help:  "Horizontal dilution of precision of the GPS fix",
+		Type:  prometheus.GaugeValue,
+		Scale: 0.01,
+	}
+	gpsV2TimeOffset = ValueDesc{
+		Index:      9,
+		Name:       "gps_time_offset",
+		Help:       "GPS time minus the time of the host running the exporter in seconds",
+		Type:       prometheus.GaugeValue,
+		Unit:       "s",
+		HAType:     "sensor",
+		MetricName: "brickd_gps_time_offset_seconds",
+	}
+)
+
+type gpsV2Bricklet struct{}
+
+func (gpsV2Bricklet) DeviceIdentifier() uint16 { return gps_v2_bricklet.DeviceIdentifier }
+func (gpsV2Bricklet) Name() string             { return "gps_bricklet_v2" }
+
+func (gpsV2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{
+		gpsV2Latitude,
+		gpsV2Longitude,
+		gpsV2Altitude,
+		gpsV2Speed,
+		gpsV2Course,
+		gpsV2SatellitesInView,
+		gpsV2SatellitesUsed,
+		gpsV2Fix,
+		gpsV2HDOP,
+		gpsV2TimeOffset,
+	}
+}
+
+func (gps gpsV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := gps_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect GPS Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	coordID := d.RegisterCoordinatesCallback(func(latitude uint32, ns rune, longitude uint32, ew rune) {
+		gps.sendCoordinates(b, dev, latitude, ns, longitude, ew)
+	})
+	d.SetCoordinatesCallbackPeriod(b.CallbackPeriod)
+
+	statusID := d.RegisterStatusCallback(func(_ bool, satellitesView uint8) {
+		b.Send(dev, 0, gpsV2SatellitesInView, float64(satellitesView))
+	})
+	d.SetStatusCallbackPeriod(b.CallbackPeriod)
+
+	altID := d.RegisterAltitudeCallback(func(altitude int32, _ int32) {
+		b.Send(dev, 0, gpsV2Altitude, float64(altitude))
+	})
+	d.SetAltitudeCallbackPeriod(b.CallbackPeriod)
+
+	motionID := d.RegisterMotionCallback(func(course uint32, speed uint32) {
+		gps.sendMotion(b, dev, course, speed)
+	})
+	d.SetMotionCallbackPeriod(b.CallbackPeriod)
+
+	dateTimeID := d.RegisterDateTimeCallback(func(date uint32, tm uint32) {
+		now := time.Now()
+		if t, ok := gpsTime(date, tm); ok {
+			b.Send(dev, 0, gpsV2TimeOffset, t.Sub(now).Seconds())
+		}
+	})
+	d.SetDateTimeCallbackPeriod(b.CallbackPeriod)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterCoordinatesCallback,
+			ID:         coordID,
+		},
+		{
+			Deregister: d.DeregisterStatusCallback,
+			ID:         statusID,
+		},
+		{
+			Deregister: d.DeregisterAltitudeCallback,
+			ID:         altID,
+		},
+		{
+			Deregister: d.DeregisterMotionCallback,
+			ID:         motionID,
+		},
+		{
+			Deregister: d.DeregisterDateTimeCallback,
+			ID:         dateTimeID,
+		},
+		b.poll(make(chan struct{}), func() {
+			gps.readPosition(b, &d, dev)
+			gps.readSatellites(b, &d, dev)
+		}),
+	}, nil
+}
+
+// sendCoordinates sends the coordinates, negative in the southern and western hemispheres
+func (gpsV2Bricklet) sendCoordinates(b *BrickdCollector, dev *Device, latitude uint32, ns rune, longitude uint32, ew rune) {
+	lat, long := float64(latitude), float64(longitude)
+	if ns == 'S' {
+		lat = -lat
+	}
+	if ew == 'W' {
+		long = -long
+	}
+	b.Send(dev, 0, gpsV2Latitude, lat)
+	b.Send(dev, 0, gpsV2Longitude, long)
+}
+
+// sendMotion sends the course and speed over ground
+func (gpsV2Bricklet) sendMotion(b *BrickdCollector, dev *Device, course, speed uint32) {
+	b.Send(dev, 0, gpsV2Course, float64(course))
+	b.Send(dev, 0, gpsV2Speed, float64(speed))
+}
+
+// readPosition reads the status and, with a fix, the coordinates, altitude and motion. The
+// callbacks of these values are only triggered when they change, so they are read every
+// callback period to not expire while the bricklet does not move.
+func (gps gpsV2Bricklet) readPosition(b *BrickdCollector, d *gps_v2_bricklet.GPSV2Bricklet, dev *Device) {
+	hasFix, satellitesView, err := d.GetStatus()
+	if err != nil {
+		log.Infof("failed to get status of GPS Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+		return
+	}
+	b.Send(dev, 0, gpsV2SatellitesInView, float64(satellitesView))
+	if !hasFix { // the position is only valid with a fix
+		return
+	}
+
+	latitude, ns, longitude, ew, err := d.GetCoordinates()
+	if err != nil {
+		log.Infof("failed to get coordinates of GPS Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+		return
+	}
+	gps.sendCoordinates(b, dev, latitude, ns, longitude, ew)
+
+	altitude, _, err := d.GetAltitude()
+	if err != nil {
+		log.Infof("failed to get altitude of GPS Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+		return
+	}
+	b.Send(dev, 0, gpsV2Altitude, float64(altitude))
+
+	course, speed, err := d.GetMotion()
+	if err != nil {
+		log.Infof("failed to get motion of GPS Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+		return
+	}
+	gps.sendMotion(b, dev, course, speed)
+}
+
+// readSatellites reads the fix and the satellites used of the GPS and GLONASS systems
+func (gpsV2Bricklet) readSatellites(b *BrickdCollector, d *gps_v2_bricklet.GPSV2Bricklet, dev *Device) {
+	var used int
+	for _, system := range []gps_v2_bricklet.SatelliteSystem{gps_v2_bricklet.SatelliteSystemGPS, gps_v2_bricklet.SatelliteSystemGLONASS} {
+		numbers, fix, _, hdop, _, err := d.GetSatelliteSystemStatus(system)
+		if err != nil {
+			log.Infof("failed to get satellite system status of GPS Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+			return
+		}
+		for _, n := range numbers {
+			if n != 0 { // 0 is not a valid satellite number
+				used++
+			}
+		}
+		if system == gps_v2_bricklet.SatelliteSystemGPS {
+			b.Send(dev, 0, gpsV2Fix, float64(fix))
+			b.Send(dev, 0, gpsV2HDOP, float64(hdop))
+		}
+	}
+	b.Send(dev, 0, gpsV2SatellitesUsed, float64(used))
+}
+
+// gpsTime returns the UTC time of the date (ddmmyy) and time (hhmmssSSS) of the GPS
+// Bricklet, ok is false when the bricklet has no time yet
+func gpsTime(date, tm uint32) (t time.Time, ok bool) {
+	if date == 0 {
+		return t, false
+	}
+	day, month, year := int(date/10000), time.Month(date/100%100), 2000+int(date%100)
+	hour, minute, sec, ms := int(tm/10000000), int(tm/100000%100), int(tm/1000%100), int(tm%1000)
+	return time.Date(year, month, day, hour, minute, sec, ms*int(time.Millisecond), time.UTC), true
+}
@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Tinkerforge/go-api-bindings/hall_effect_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(hallEffectV2Bricklet{})
+}
+
+var (
+	hallEffectV2FluxDensity = ValueDesc{
+		Index:  0,
+		Name:   "magnetic_flux_density",
+		Help:   "Magnetic flux density in µT",
+		Type:   prometheus.GaugeValue,
+		Unit:   "µT",
+		HAType: "sensor",
+	}
+	hallEffectV2Door = ValueDesc{
+		Index:       1,
+		Name:        "door",
+		Help:        "Door open (1) when no magnet is detected by the Hall Effect Bricklet 2.0, closed (0) otherwise",
+		Type:        prometheus.GaugeValue,
+		HAType:      "binary_sensor",
+		DeviceClass: "door",
+	}
+	hallEffectV2Events = eventCount(2, "door_events", "Number of times the door was opened or closed")
+)
+
+// hallEffectV2MaxPeriod is the longest callback period of the magnetic flux density in ms,
+// short enough to notice opening and closing the door right away
+const hallEffectV2MaxPeriod uint32 = 200
+
+type hallEffectV2Bricklet struct{}
+
+func (hallEffectV2Bricklet) DeviceIdentifier() uint16 {
+	return hall_effect_v2_bricklet.DeviceIdentifier
+}
+func (hallEffectV2Bricklet) Name() string { return "hall_effect_bricklet_v2" }
+
+func (hallEffectV2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{hallEffectV2FluxDensity, hallEffectV2Door, hallEffectV2Events}
+}
+
+func (hallEffectV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := hall_effect_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Hall Effect Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	// a magnet is detected with the thresholds of the counter of the bricklet, which can be
+	// changed with the Brick Viewer
+	high, low, _, err := d.GetCounterConfig()
+	if err != nil {
+		log.Infof("failed to get counter config of Hall Effect Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+		high, low = 2000, -2000 // the defaults of the bricklet
+	}
+
+	var mu sync.Mutex
+	var known, open bool
+	fluxID := d.RegisterMagneticFluxDensityCallback(func(density int16) {
+		b.Send(dev, 0, hallEffectV2FluxDensity, float64(density))
+
+		mu.Lock()
+		defer mu.Unlock()
+		magnet := density >= high || density <= low
+		switch {
+		case !known:
+			known, open = true, !magnet
+			b.SendState(dev, 0, hallEffectV2Door, bool2Float(open))
+		case open == magnet:
+			open = !magnet
+			b.SendEvent(dev, 0, hallEffectV2Door, bool2Float(open), hallEffectV2Events)
+		}
+	})
+	d.SetMagneticFluxDensityCallbackConfiguration(min(b.CallbackPeriod, hallEffectV2MaxPeriod), false, 'x', 0, 0)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	// the door is only sent on changes by the callback
+	sendDoor := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if known {
+			b.Send(dev, 0, hallEffectV2Door, bool2Float(open))
+		}
+	}
+
+	return []Register{
+		{
+			Deregister: d.DeregisterMagneticFluxDensityCallback,
+			ID:         fluxID,
+		},
+		b.repeatEvents(dev, 0, hallEffectV2Events, sendDoor),
+	}, nil
+}
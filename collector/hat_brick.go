@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/hat_brick"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(hatBrick{})
+}
+
+var (
+	hatVoltageUSB = ValueDesc{
+		Index: 0,
+		Name:  "voltage_usb",
+		Help:  "Voltage of the Hat USB in V",
+		Type:  prometheus.GaugeValue,
+		Unit:  "V",
+		Scale: 0.001,
+	}
+	hatVoltageDC = ValueDesc{
+		Index: 1,
+		Name:  "voltage_dc",
+		Help:  "Voltage of the Hat DC in V",
+		Type:  prometheus.GaugeValue,
+		Unit:  "V",
+		Scale: 0.001,
+	}
+)
+
+type hatBrick struct{}
+
+func (hatBrick) DeviceIdentifier() uint16 { return hat_brick.DeviceIdentifier }
+func (hatBrick) Name() string             { return "hat_brick" }
+func (hatBrick) Values() []ValueDesc      { return []ValueDesc{hatVoltageUSB, hatVoltageDC} }
+
+func (hatBrick) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	h, err := hat_brick.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Hat Brick (uid=%s): %s", dev.UID, err)
+	}
+
+	callbackID := h.RegisterVoltagesCallback(func(voltageUSB uint16, voltageDC uint16) {
+		b.Send(dev, 0, hatVoltageUSB, float64(voltageUSB))
+		b.Send(dev, 0, hatVoltageDC, float64(voltageDC))
+	})
+	h.SetVoltagesCallbackConfiguration(b.CallbackPeriod, false)
+
+	b.setStatusLED(dev, h.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: h.DeregisterVoltagesCallback,
+			ID:         callbackID,
+		},
+	}, nil
+}
@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/hat_zero_brick"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(hatZeroBrick{})
+}
+
+var hatZeroVoltage = ValueDesc{
+	Index: 0,
+	Name:  "voltage",
+	Help:  "Voltage in V",
+	Type:  prometheus.GaugeValue,
+	Unit:  "V",
+	Scale: 0.001,
+}
+
+type hatZeroBrick struct{}
+
+func (hatZeroBrick) DeviceIdentifier() uint16 { return hat_zero_brick.DeviceIdentifier }
+func (hatZeroBrick) Name() string             { return "hat_zero_brick" }
+func (hatZeroBrick) Values() []ValueDesc      { return []ValueDesc{hatZeroVoltage} }
+
+func (hatZeroBrick) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	h, err := hat_zero_brick.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Zero Hat Brick (uid=%s): %s", dev.UID, err)
+	}
+
+	vID := h.RegisterUSBVoltageCallback(func(voltage uint16) {
+		b.Send(dev, 0, hatZeroVoltage, float64(voltage))
+	})
+	h.SetUSBVoltageCallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	b.setStatusLED(dev, h.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: h.DeregisterUSBVoltageCallback,
+			ID:         vID,
+		},
+	}, nil
+}
@@ -10,44 +10,119 @@ import (
 
 // SetHAConfig writes the HomeAssistant config to MQTT
 // Parameters:
-// * typ - HA type, probably either "sensor" or "binary_sensor"
+// * typ - HA type, "sensor", "binary_sensor", "switch" or "device_tracker"
 // * devClass - type of sensor, must be a valid HA device class
 // * valueName - name of the value inside the JSON of the MQTT topic we're publishing to
 // * unit - HA unit
+// * stateClass - HA state class, e.g. "total_increasing" for counters, may be empty
 // * uniqueID - make these sensors unique
 // * dev - the *Device
 // * idx - unless there can be multiple sensors (like in the Outdoor Weather Bricklet) this is 0
 // * deviceID - make a new "device" when not empty, just used in the Outdoor Weather Bricklet, otherwise ""
-func (b *BrickdCollector) SetHAConfig(typ, devClass, valueName, unit, uniqueID string, dev *Device, idx int, deviceID string) {
-	if b.MQTT == nil || !b.MQTT.Enabled || !b.MQTT.HomeAssistant.Enabled {
+func (b *BrickdCollector) SetHAConfig(typ, devClass, valueName, unit, stateClass, uniqueID string, dev *Device, idx int64, deviceID string) {
+	b.Lock()
+	mq := b.MQTT
+	if mq == nil || !mq.Enabled || !mq.HomeAssistant.Enabled || mq.Client == nil {
+		b.Unlock()
 		return
 	}
-	b.setHAConfig(typ, devClass, valueName, unit, uniqueID, dev, idx, deviceID)
-	if b.MQTT.HomeAssistant.Interval == 0 {
+	topic := haConfigTopic(mq.HomeAssistant.DiscoveryBase, typ, uniqueID, valueName)
+	b.stopHARepeater(topic)
+	var stop chan struct{}
+	if mq.HomeAssistant.Interval != 0 {
+		stop = make(chan struct{})
+		b.haRepeaters[topic] = stop
+	}
+	b.Unlock()
+
+	b.setHAConfig(typ, devClass, valueName, unit, stateClass, uniqueID, dev, idx, deviceID)
+	if stop == nil {
 		return
 	}
 
-	go func(typ, devClass, valueName, unit, uniqueID string, dev *Device, idx int, deviceID string) {
-		time.Sleep(b.MQTT.HomeAssistant.Interval)
-		for _ = range time.Tick(b.MQTT.HomeAssistant.Interval) {
-			b.setHAConfig(typ, devClass, valueName, unit, uniqueID, dev, idx, deviceID)
+	go func(typ, devClass, valueName, unit, stateClass, uniqueID string, dev *Device, idx int64, deviceID string) {
+		ticker := time.NewTicker(mq.HomeAssistant.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.done:
+				return
+			case <-stop:
+				return
+			case <-ticker.C:
+				b.setHAConfig(typ, devClass, valueName, unit, stateClass, uniqueID, dev, idx, deviceID)
+			}
 		}
-	}(typ, devClass, valueName, unit, uniqueID, dev, idx, deviceID)
+	}(typ, devClass, valueName, unit, stateClass, uniqueID, dev, idx, deviceID)
+}
+
+// RemoveHAConfig stops publishing the HomeAssistant config set by SetHAConfig and publishes
+// an empty config, which removes the entity from HomeAssistant
+func (b *BrickdCollector) RemoveHAConfig(typ, valueName, uniqueID string) {
+	b.Lock()
+	mq := b.MQTT
+	if mq == nil || !mq.Enabled || !mq.HomeAssistant.Enabled || mq.Client == nil {
+		b.Unlock()
+		return
+	}
+	topic := haConfigTopic(mq.HomeAssistant.DiscoveryBase, typ, uniqueID, valueName)
+	b.stopHARepeater(topic)
+	b.Unlock()
+
+	log.Infof("removing HA config %s", topic)
+	go mq.Client.Publish(topic, []byte{})
 }
 
-func (b *BrickdCollector) setHAConfig(typ, devClass, valueName, unit, uniqueID string, dev *Device, idx int, deviceID string) {
-	topic := b.MQTT.HomeAssistant.DiscoveryBase
-	if topic == "" {
-		topic = "homeassistant/"
+// stopHARepeater stops republishing the HA config of the topic, b must be locked
+func (b *BrickdCollector) stopHARepeater(topic string) {
+	if stop, ok := b.haRepeaters[topic]; ok {
+		close(stop)
+		delete(b.haRepeaters, topic)
 	}
-	topic += typ + "/brickd_" + uniqueID + "_" + valueName + "/config"
+}
+
+// haConfigTopic returns the topic of the HA config of a value
+func haConfigTopic(discoveryBase, typ, uniqueID, valueName string) string {
+	if discoveryBase == "" {
+		discoveryBase = "homeassistant/"
+	}
+	return discoveryBase + typ + "/brickd_" + uniqueID + "_" + valueName + "/config"
+}
+
+func (b *BrickdCollector) setHAConfig(typ, devClass, valueName, unit, stateClass, uniqueID string, dev *Device, idx int64, deviceID string) {
+	b.RLock()
+	mq := b.MQTT
+	b.RUnlock()
+	topic := haConfigTopic(mq.HomeAssistant.DiscoveryBase, typ, uniqueID, valueName)
+	cfg := b.haConfig(typ, devClass, valueName, unit, stateClass, uniqueID, dev, idx, deviceID)
+	enc, err := json.Marshal(cfg)
+	if err != nil {
+		log.Errorf("failed to marshal HA Config: %s", err)
+		return
+	}
+	log.Infof("publishing HA config to %s: %s", topic, string(enc))
+	go mq.Client.Publish(topic, enc)
+}
+
+// haConfig returns the HomeAssistant config of a value, see SetHAConfig for the parameters.
+// The state topic is the topic the values of the sensor are published on, see SensorTopic.
+func (b *BrickdCollector) haConfig(typ, devClass, valueName, unit, stateClass, uniqueID string, dev *Device, idx int64, deviceID string) *HAConfig {
+	b.RLock()
+	mq := b.MQTT
+	stateTopic := mq.Topic.Name(b.SensorTopic(dev, idx))
+	var commandTopic string
+	if typ == "switch" {
+		commandTopic = mq.Topic.Name(b.CommandTopic(dev, idx))
+	}
+	b.RUnlock()
+
 	id := b.DefaultTopic(dev)
 	if deviceID != "" {
 		id += "_" + deviceID
 	}
 
 	valueTemplate := fmt.Sprintf("{{ value_json.%s }}", valueName)
-	if typ == "binary_sensor" {
+	if typ == "binary_sensor" || typ == "switch" {
 		valueTemplate = fmt.Sprintf("{%% if value_json.%s == 0 %%}OFF{%% else %%}ON{%% endif %%}", valueName)
 	}
 	cfg := &HAConfig{
@@ -55,8 +130,10 @@ func (b *BrickdCollector) setHAConfig(typ, devClass, valueName, unit, uniqueID s
 		UniqueID:          "brickd_" + uniqueID + "_" + valueName,
 		ObjectID:          "brickd_" + uniqueID + "_" + valueName,
 		Name:              "brickd_" + uniqueID + "_" + valueName,
-		StateTopic:        string(b.MQTT.Topic) + b.SensorTopic(dev, idx),
+		StateTopic:        stateTopic,
+		CommandTopic:      commandTopic,
 		UnitOfMeasurement: unit,
+		StateClass:        stateClass,
 		ValueTemplate:     valueTemplate,
 		Device: HADevice{
 			Name:         "Brickd: " + b.Address + " / " + DeviceName(dev.DeviceID),
@@ -72,25 +149,34 @@ func (b *BrickdCollector) setHAConfig(typ, devClass, valueName, unit, uniqueID s
 			SupportURL: "https://github.com/vetinari/brickd_exporter",
 		},
 	}
-	enc, err := json.Marshal(cfg)
-	if err != nil {
-		log.Errorf("failed to marshal HA Config: %s", err)
-		return
+	if typ == "device_tracker" {
+		// the location is set from the latitude and longitude attributes, the state (home,
+		// not_home or a zone) is derived from the location by HA
+		cfg.StateTopic = ""
+		cfg.ValueTemplate = ""
+		cfg.UnitOfMeasurement = ""
+		cfg.JSONAttributesTopic = stateTopic
+		cfg.JSONAttributesTemplate = "{{ {'latitude': value_json.latitude, 'longitude': value_json.longitude} | tojson }}"
+		cfg.SourceType = "gps"
 	}
-	log.Infof("publishing HA config to %s: %s", topic, string(enc))
-	go b.MQTT.Client.Publish(topic, enc)
+	return cfg
 }
 
 type HAConfig struct {
-	Name              string   `json:"name"`
-	DeviceClass       string   `json:"device_class"`
-	StateTopic        string   `json:"state_topic"`
-	UnitOfMeasurement string   `json:"unit_of_measurement"`
-	ValueTemplate     string   `json:"value_template"`
-	UniqueID          string   `json:"unique_id"`
-	ObjectID          string   `json:"object_id"`
-	Device            HADevice `json:"device"`
-	Origin            HAOrigin `json:"origin"`
+	Name                   string   `json:"name"`
+	DeviceClass            string   `json:"device_class,omitempty"`
+	StateTopic             string   `json:"state_topic,omitempty"`
+	CommandTopic           string   `json:"command_topic,omitempty"`
+	UnitOfMeasurement      string   `json:"unit_of_measurement"`
+	StateClass             string   `json:"state_class,omitempty"`
+	ValueTemplate          string   `json:"value_template,omitempty"`
+	JSONAttributesTopic    string   `json:"json_attributes_topic,omitempty"`
+	JSONAttributesTemplate string   `json:"json_attributes_template,omitempty"`
+	SourceType             string   `json:"source_type,omitempty"`
+	UniqueID               string   `json:"unique_id"`
+	ObjectID               string   `json:"object_id"`
+	Device                 HADevice `json:"device"`
+	Origin                 HAOrigin `json:"origin"`
 }
 
 type HAOrigin struct {
@@ -0,0 +1,227 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DeviceInfo is the JSON representation of a discovered device in the /devices listing
+type DeviceInfo struct {
+	Brickd           string    `json:"brickd"`
+	UID              string    `json:"uid"`
+	ConnectedUID     string    `json:"connected_uid"`
+	Position         string    `json:"position"`
+	DeviceIdentifier uint16    `json:"device_identifier"`
+	Type             string    `json:"type"`
+	HardwareVersion  string    `json:"hardware_version"`
+	FirmwareVersion  string    `json:"firmware_version"`
+	Available        bool      `json:"available"`
+	Supported        bool      `json:"supported"`
+	LastEnumerated   time.Time `json:"last_enumerated"`
+}
+
+// DeviceList returns all devices discovered on all brickd daemons, sorted by brickd and UID
+func (m *MultiCollector) DeviceList() []DeviceInfo {
+	list := []DeviceInfo{}
+	for _, c := range m.Collectors {
+		c.RLock()
+		for _, dev := range c.Data.Devices {
+			if c.ignored(dev.UID) {
+				continue
+			}
+			list = append(list, DeviceInfo{
+				Brickd:           c.Data.Address,
+				UID:              dev.UID,
+				ConnectedUID:     dev.ConnectedUID,
+				Position:         dev.positionString(),
+				DeviceIdentifier: dev.DeviceID,
+				Type:             DeviceName(dev.DeviceID),
+				HardwareVersion:  dev.HardwareVersion,
+				FirmwareVersion:  dev.FirmwareVersion,
+				Available:        dev.Available,
+				Supported:        dev.Supported,
+				LastEnumerated:   dev.LastEnumerated,
+			})
+		}
+		c.RUnlock()
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Brickd != list[j].Brickd {
+			return list[i].Brickd < list[j].Brickd
+		}
+		return list[i].UID < list[j].UID
+	})
+	return list
+}
+
+// DevicesHandler serves the list of discovered devices as JSON
+func (m *MultiCollector) DevicesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(m.DeviceList()); err != nil {
+		log.Errorf("failed to encode device list: %s", err)
+	}
+}
+
+// ledStatusRequest is the JSON body of the /api/devices/{uid}/led endpoint
+type ledStatusRequest struct {
+	UID       string `json:"uid"`
+	LEDStatus string `json:"led_status"`
+}
+
+// scaleRequest is the JSON body of the /api/devices/{uid}/tare and /api/devices/{uid}/calibrate
+// endpoints, the weight is in kg
+type scaleRequest struct {
+	UID    string  `json:"uid"`
+	Weight float64 `json:"weight"`
+}
+
+// APIHandler serves the device API:
+//   - /api/devices/{uid}/led: GET returns the LED status of the device, PUT or POST with a
+//     JSON body like {"led_status": "off"} changes it
+//   - /api/devices/{uid}/tare: POST tares a load cell and returns the tare like {"weight": 12.5}
+//   - /api/devices/{uid}/calibrate: POST with a JSON body like {"weight": 10} calibrates a
+//     load cell with a known weight
+//
+// The API is not authenticated, so tare and calibrate are only served with AdminAPI enabled.
+func (m *MultiCollector) APIHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/devices/"), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	uid := parts[0]
+
+	var handler func(http.ResponseWriter, *http.Request, *BrickdCollector, string)
+	var admin bool
+	switch parts[1] {
+	case "led":
+		handler = ledHandler
+	case "tare":
+		handler, admin = tareHandler, true
+	case "calibrate":
+		handler, admin = calibrateHandler, true
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if admin && !m.AdminAPI {
+		http.Error(w, "admin API is not enabled", http.StatusForbidden)
+		return
+	}
+
+	var c *BrickdCollector
+	for _, bc := range m.Collectors {
+		bc.RLock()
+		_, ok := bc.Data.Devices[uid]
+		ok = ok && !bc.ignored(uid)
+		bc.RUnlock()
+		if ok {
+			c = bc
+			break
+		}
+	}
+	if c == nil {
+		http.Error(w, fmt.Sprintf("%s: %s", ErrUnknownDevice, uid), http.StatusNotFound)
+		return
+	}
+	handler(w, r, c, uid)
+}
+
+// ledHandler serves /api/devices/{uid}/led
+func ledHandler(w http.ResponseWriter, r *http.Request, c *BrickdCollector, uid string) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var req ledStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode request: %s", err), http.StatusBadRequest)
+			return
+		}
+		if req.LEDStatus == "" {
+			http.Error(w, "missing led_status", http.StatusBadRequest)
+			return
+		}
+		if err := ValidLEDStatus(req.LEDStatus); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := c.SetLEDStatus(uid, req.LEDStatus); err != nil {
+			httpError(w, err)
+			return
+		}
+		log.Infof("LED status of %s set to %q", uid, req.LEDStatus)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status, err := c.DeviceLED(uid)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ledStatusRequest{UID: uid, LEDStatus: status}); err != nil {
+		log.Errorf("failed to encode LED status: %s", err)
+	}
+}
+
+// tareHandler serves /api/devices/{uid}/tare
+func tareHandler(w http.ResponseWriter, r *http.Request, c *BrickdCollector, uid string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tare, err := c.Tare(uid)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(scaleRequest{UID: uid, Weight: tare}); err != nil {
+		log.Errorf("failed to encode tare: %s", err)
+	}
+}
+
+// calibrateHandler serves /api/devices/{uid}/calibrate
+func calibrateHandler(w http.ResponseWriter, r *http.Request, c *BrickdCollector, uid string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req scaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode request: %s", err), http.StatusBadRequest)
+		return
+	}
+	if err := c.Calibrate(uid, req.Weight); err != nil {
+		httpError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(scaleRequest{UID: uid, Weight: req.Weight}); err != nil {
+		log.Errorf("failed to encode calibration: %s", err)
+	}
+}
+
+// httpError maps the errors of the collector to HTTP status codes
+func httpError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrUnknownDevice):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrNoStatusLED), errors.Is(err, ErrNoScale), errors.Is(err, ErrInvalidWeight):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
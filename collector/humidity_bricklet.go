@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/humidity_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(humidityBricklet{})
+}
+
+var humidityHumidity = ValueDesc{
+	Index:       0,
+	Name:        "humidity",
+	Help:        "Humidity of the air in %rH",
+	Type:        prometheus.GaugeValue,
+	Unit:        "%",
+	Scale:       0.1,
+	HAType:      "sensor",
+	DeviceClass: "humidity",
+}
+
+type humidityBricklet struct{}
+
+func (humidityBricklet) DeviceIdentifier() uint16 { return humidity_bricklet.DeviceIdentifier }
+func (humidityBricklet) Name() string             { return "humidity_bricklet" }
+func (humidityBricklet) Values() []ValueDesc      { return []ValueDesc{humidityHumidity} }
+
+func (humidityBricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := humidity_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Humidity Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	callbackID := d.RegisterHumidityCallback(func(humidity uint16) {
+		b.Send(dev, 0, humidityHumidity, float64(humidity))
+	})
+	d.SetHumidityCallbackPeriod(b.CallbackPeriod)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterHumidityCallback,
+			ID:         callbackID,
+		},
+	}, nil
+}
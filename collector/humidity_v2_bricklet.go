@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/humidity_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(humidityV2Bricklet{})
+}
+
+var (
+	humidityV2Humidity = ValueDesc{
+		Index:       0,
+		Name:        "humidity",
+		Help:        "Humidity of the air in %rH",
+		Type:        prometheus.GaugeValue,
+		Unit:        "%",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "humidity",
+	}
+	humidityV2Temperature = ValueDesc{
+		Index:       1,
+		Name:        "temperature",
+		Help:        "Temperature in °C",
+		Type:        prometheus.GaugeValue,
+		Unit:        "°C",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "temperature",
+	}
+)
+
+type humidityV2Bricklet struct{}
+
+func (humidityV2Bricklet) DeviceIdentifier() uint16 { return humidity_v2_bricklet.DeviceIdentifier }
+func (humidityV2Bricklet) Name() string             { return "humidity_bricklet_v2" }
+
+func (humidityV2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{humidityV2Humidity, humidityV2Temperature}
+}
+
+func (humidityV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := humidity_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Humidity Bricklet V2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	humID := d.RegisterHumidityCallback(func(humidity uint16) {
+		b.Send(dev, 0, humidityV2Humidity, float64(humidity))
+	})
+	d.SetHumidityCallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	tempID := d.RegisterTemperatureCallback(func(temperature int16) {
+		b.Send(dev, 0, humidityV2Temperature, float64(temperature))
+	})
+	d.SetTemperatureCallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterHumidityCallback,
+			ID:         humID,
+		},
+		{
+			Deregister: d.DeregisterTemperatureCallback,
+			ID:         tempID,
+		},
+	}, nil
+}
@@ -0,0 +1,174 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// the values of the IMU Brick 2.0 and IMU Bricklet 3.0, which have the same API. The values
+// are sent by the all data callback every callback period. With VibrationSettings, the
+// linear acceleration callback is also registered at the sample rate for the vibration
+// summary.
+
+var (
+	imuHeading = ValueDesc{
+		Index:  0,
+		Name:   "heading",
+		Help:   "Heading of the IMU in °",
+		Type:   prometheus.GaugeValue,
+		Unit:   "°",
+		Scale:  1.0 / 16,
+		HAType: "sensor",
+	}
+	imuRoll = ValueDesc{
+		Index:  1,
+		Name:   "roll",
+		Help:   "Roll of the IMU in °",
+		Type:   prometheus.GaugeValue,
+		Unit:   "°",
+		Scale:  1.0 / 16,
+		HAType: "sensor",
+	}
+	imuPitch = ValueDesc{
+		Index:  2,
+		Name:   "pitch",
+		Help:   "Pitch of the IMU in °",
+		Type:   prometheus.GaugeValue,
+		Unit:   "°",
+		Scale:  1.0 / 16,
+		HAType: "sensor",
+	}
+	// imuLinearAcceleration is sent for each axis, see axes
+	imuLinearAcceleration = ValueDesc{
+		Index:  3,
+		Name:   "linear_acceleration",
+		Help:   "Acceleration without gravity in m/s²",
+		Type:   prometheus.GaugeValue,
+		Unit:   "m/s²",
+		Scale:  0.01,
+		Labels: []string{"axis"},
+	}
+	imuTemperature = ValueDesc{
+		Index:       6,
+		Name:        "imu_temperature",
+		Help:        "Temperature of the IMU in °C",
+		Type:        prometheus.GaugeValue,
+		Unit:        "°C",
+		HAType:      "sensor",
+		DeviceClass: "temperature",
+	}
+	// imuCalibration are the calibration status of the sensors from the lowest two bits of
+	// the calibration status to the highest
+	imuCalibration = []ValueDesc{
+		{
+			Index: 7,
+			Name:  "imu_calibration_magnetometer",
+			Help:  "Calibration status of the magnetometer of the IMU, 0 (uncalibrated) to 3 (fully calibrated)",
+			Type:  prometheus.GaugeValue,
+		},
+		{
+			Index: 8,
+			Name:  "imu_calibration_accelerometer",
+			Help:  "Calibration status of the accelerometer of the IMU, 0 (uncalibrated) to 3 (fully calibrated)",
+			Type:  prometheus.GaugeValue,
+		},
+		{
+			Index: 9,
+			Name:  "imu_calibration_gyroscope",
+			Help:  "Calibration status of the gyroscope of the IMU, 0 (uncalibrated) to 3 (fully calibrated)",
+			Type:  prometheus.GaugeValue,
+		},
+		{
+			Index: 10,
+			Name:  "imu_calibration_system",
+			Help:  "Calibration status of the sensor fusion of the IMU, 0 (uncalibrated) to 3 (fully calibrated)",
+			Type:  prometheus.GaugeValue,
+		},
+	}
+	imuVibration = vibrationValues(11)
+)
+
+// imuValues returns the values of an IMU
+func imuValues() []ValueDesc {
+	values := []ValueDesc{imuHeading, imuRoll, imuPitch, imuLinearAcceleration, imuTemperature}
+	values = append(values, imuCalibration...)
+	return append(values, imuVibration...)
+}
+
+// imuAPI are the functions of the bindings of an IMU used by registerIMU
+type imuAPI struct {
+	registerAllData   func(func(acc, mag, gyr, euler [3]int16, quaternion [4]int16, linear, gravity [3]int16, temperature int8, calibration uint8)) uint64
+	deregisterAllData func(uint64)
+	setAllDataPeriod  func(period uint32) error
+	registerLinear    func(func(x, y, z int16)) uint64
+	deregisterLinear  func(uint64)
+	setLinearPeriod   func(period uint32) error
+	defaultSampleRate int // samples per second of the vibration summary with a sample rate of 0, also the maximum
+}
+
+// registerIMU registers the callbacks of an IMU
+func (b *BrickdCollector) registerIMU(dev *Device, d imuAPI) []Register {
+	allID := d.registerAllData(func(_, _, _, euler [3]int16, _ [4]int16, linear, _ [3]int16, temperature int8, calibration uint8) {
+		b.Send(dev, 0, imuHeading, float64(euler[0]))
+		b.Send(dev, 0, imuRoll, float64(euler[1]))
+		b.Send(dev, 0, imuPitch, float64(euler[2]))
+		for i, acc := range linear {
+			desc := imuLinearAcceleration
+			desc.Index += i
+			b.SendLabels(dev, 0, desc, float64(acc), map[string]string{"axis": axes[i]})
+		}
+		b.Send(dev, 0, imuTemperature, float64(temperature))
+		for i, desc := range imuCalibration {
+			b.Send(dev, 0, desc, float64(calibration>>(2*i)&0x3))
+		}
+	})
+	if err := d.setAllDataPeriod(b.CallbackPeriod); err != nil {
+		log.Errorf("failed to set all data period of %s (uid=%s): %s", DeviceName(dev.DeviceID), dev.UID, err)
+	}
+	registers := []Register{
+		{
+			Deregister: d.deregisterAllData,
+			ID:         allID,
+		},
+	}
+
+	b.RLock()
+	s, ok := b.Vibration[dev.UID]
+	b.RUnlock()
+	if !ok {
+		// the period is kept by the device when the vibration settings are removed
+		if err := d.setLinearPeriod(0); err != nil {
+			log.Errorf("failed to disable linear acceleration of %s (uid=%s): %s", DeviceName(dev.DeviceID), dev.UID, err)
+		}
+		return registers
+	}
+	rate := s.SampleRate
+	if rate > d.defaultSampleRate {
+		log.Warnf("sample_rate %d of %s (uid=%s) is higher than the %d Hz of the sensor fusion, using %d Hz",
+			rate, DeviceName(dev.DeviceID), dev.UID, d.defaultSampleRate, d.defaultSampleRate)
+		rate = d.defaultSampleRate
+	}
+	if rate == 0 {
+		rate = d.defaultSampleRate
+	}
+	period := time.Second / time.Duration(rate) / time.Millisecond
+	log.Debugf("sampling %s (uid=%s) every %d ms", DeviceName(dev.DeviceID), dev.UID, period)
+
+	var v vibration
+	linearID := d.registerLinear(func(x, y, z int16) {
+		v.add([3]float64{float64(x) / 100, float64(y) / 100, float64(z) / 100})
+	})
+	if err := d.setLinearPeriod(uint32(period)); err != nil {
+		log.Errorf("failed to set linear acceleration period of %s (uid=%s): %s", DeviceName(dev.DeviceID), dev.UID, err)
+	}
+
+	return append(registers,
+		Register{
+			Deregister: d.deregisterLinear,
+			ID:         linearID,
+		},
+		b.sendVibration(dev, &v, imuVibration, nil),
+	)
+}
@@ -0,0 +1,37 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/imu_v2_brick"
+)
+
+func init() {
+	RegisterDriver(imuV2Brick{})
+}
+
+type imuV2Brick struct{}
+
+func (imuV2Brick) DeviceIdentifier() uint16 { return imu_v2_brick.DeviceIdentifier }
+func (imuV2Brick) Name() string             { return "imu_brick_v2" }
+
+func (imuV2Brick) Values() []ValueDesc {
+	return imuValues()
+}
+
+func (imuV2Brick) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := imu_v2_brick.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect IMU Brick 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	return b.registerIMU(dev, imuAPI{
+		registerAllData:   d.RegisterAllDataCallback,
+		deregisterAllData: d.DeregisterAllDataCallback,
+		setAllDataPeriod:  d.SetAllDataPeriod,
+		registerLinear:    d.RegisterLinearAccelerationCallback,
+		deregisterLinear:  d.DeregisterLinearAccelerationCallback,
+		setLinearPeriod:   d.SetLinearAccelerationPeriod,
+		defaultSampleRate: 100, // the rate of the sensor fusion
+	}), nil
+}
@@ -0,0 +1,43 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/imu_v3_bricklet"
+)
+
+func init() {
+	RegisterDriver(imuV3Bricklet{})
+}
+
+type imuV3Bricklet struct{}
+
+func (imuV3Bricklet) DeviceIdentifier() uint16 { return imu_v3_bricklet.DeviceIdentifier }
+func (imuV3Bricklet) Name() string             { return "imu_bricklet_v3" }
+
+func (imuV3Bricklet) Values() []ValueDesc {
+	return imuValues()
+}
+
+func (imuV3Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := imu_v3_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect IMU Bricklet 3.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return b.registerIMU(dev, imuAPI{
+		registerAllData:   d.RegisterAllDataCallback,
+		deregisterAllData: d.DeregisterAllDataCallback,
+		setAllDataPeriod: func(period uint32) error {
+			return d.SetAllDataCallbackConfiguration(period, false)
+		},
+		registerLinear:   d.RegisterLinearAccelerationCallback,
+		deregisterLinear: d.DeregisterLinearAccelerationCallback,
+		setLinearPeriod: func(period uint32) error {
+			return d.SetLinearAccelerationCallbackConfiguration(period, false)
+		},
+		defaultSampleRate: 100, // the rate of the sensor fusion
+	}), nil
+}
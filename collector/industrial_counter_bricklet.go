@@ -0,0 +1,217 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/Tinkerforge/go-api-bindings/industrial_counter_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(industrialCounterBricklet{})
+}
+
+// the values of the channels, the sensor id is the channel. The name, unit and scale of the
+// counter are set by the CounterSettings of the channel, see CounterSettings.counter.
+var (
+	industrialCounterCount = ValueDesc{
+		Index:      0,
+		Name:       "industrial_counter",
+		Help:       "Pulses counted on the Industrial Counter Bricklet channel divided by the configured pulses per unit",
+		Type:       prometheus.CounterValue,
+		HAType:     "sensor",
+		StateClass: "total_increasing",
+	}
+	industrialCounterFrequency = ValueDesc{
+		Index:       4,
+		Name:        "industrial_counter_frequency",
+		Help:        "Frequency of the signal on the Industrial Counter Bricklet channel in Hz",
+		Type:        prometheus.GaugeValue,
+		Unit:        "Hz",
+		Scale:       0.001,
+		HAType:      "sensor",
+		DeviceClass: "frequency",
+	}
+	industrialCounterDutyCycle = ValueDesc{
+		Index:  8,
+		Name:   "industrial_counter_duty_cycle",
+		Help:   "Duty cycle of the signal on the Industrial Counter Bricklet channel in %",
+		Type:   prometheus.GaugeValue,
+		Unit:   "%",
+		Scale:  0.01,
+		HAType: "sensor",
+	}
+	industrialCounterLevel = func() ValueDesc {
+		v := digitalInputLevel()
+		v.Index = 12
+		return v
+	}()
+)
+
+// industrialCounterEdges maps the `edge` config values to the count edge of the bricklet
+var industrialCounterEdges = map[string]uint8{
+	"rising":  industrial_counter_bricklet.CountEdgeRising,
+	"falling": industrial_counter_bricklet.CountEdgeFalling,
+	"both":    industrial_counter_bricklet.CountEdgeBoth,
+}
+
+var counterName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// CounterSettings configure a channel of an Industrial Counter Bricklet
+type CounterSettings struct {
+	Name          string  `yaml:"name"`            // exported as brickd_<name>_total, default "industrial_counter"
+	Unit          string  `yaml:"unit"`            // unit of the counter in HomeAssistant, e.g. "L"
+	DeviceClass   string  `yaml:"device_class"`    // HomeAssistant device class, e.g. "water" or "gas"
+	PulsesPerUnit float64 `yaml:"pulses_per_unit"` // the counter is divided by this, 0 is the same as 1
+	Active        *bool   `yaml:"active"`          // counting is enabled, default true
+	Edge          string  `yaml:"edge"`            // "rising", "falling" or "both", default "rising"
+}
+
+// Validate returns an error if the name, edge or pulses per unit are invalid or the name is
+// already used by a value of a driver
+func (c CounterSettings) Validate() error {
+	if c.Name != "" {
+		if !counterName.MatchString(c.Name) {
+			return fmt.Errorf("invalid name %q", c.Name)
+		}
+		for _, m := range Metrics() {
+			if m.Name == c.Name && m.Name != industrialCounterCount.Name {
+				return fmt.Errorf("name %q is already used by another value", c.Name)
+			}
+		}
+	}
+	if _, ok := industrialCounterEdges[c.Edge]; !ok && c.Edge != "" {
+		return fmt.Errorf("invalid edge %q, must be one of \"rising\", \"falling\" or \"both\"", c.Edge)
+	}
+	if c.PulsesPerUnit < 0 {
+		return fmt.Errorf("pulses_per_unit must not be negative")
+	}
+	return nil
+}
+
+// active returns whether the channel counts
+func (c CounterSettings) active() bool {
+	return c.Active == nil || *c.Active
+}
+
+// counter returns the counter value of the channel
+func (c CounterSettings) counter() ValueDesc {
+	desc := industrialCounterCount
+	if c.Name != "" {
+		desc.Name = c.Name
+	}
+	desc.Unit = c.Unit
+	desc.DeviceClass = c.DeviceClass
+	if c.PulsesPerUnit != 0 {
+		desc.Scale = 1 / c.PulsesPerUnit
+	}
+	return desc
+}
+
+// counterMetrics returns the metrics of the counters with a configured name
+func counterMetrics(counters map[string]map[string]CounterSettings) []Metric {
+	var metrics []Metric
+	for _, channels := range counters {
+		for _, c := range channels {
+			if c.Name != "" {
+				metrics = append(metrics, c.counter().Metric())
+			}
+		}
+	}
+	return metrics
+}
+
+type industrialCounterBricklet struct{}
+
+func (industrialCounterBricklet) DeviceIdentifier() uint16 {
+	return industrial_counter_bricklet.DeviceIdentifier
+}
+func (industrialCounterBricklet) Name() string { return "industrial_counter_bricklet" }
+func (industrialCounterBricklet) MultiSensor() {}
+
+func (industrialCounterBricklet) Values() []ValueDesc {
+	return []ValueDesc{
+		industrialCounterCount,
+		industrialCounterFrequency,
+		industrialCounterDutyCycle,
+		industrialCounterLevel,
+	}
+}
+
+func (ic industrialCounterBricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := industrial_counter_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Industrial Counter Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	b.RLock()
+	settings := b.Counters[dev.UID]
+	b.RUnlock()
+
+	// channels not in the config keep the configuration of the bricklet
+	active, err := d.GetAllCounterActive()
+	if err != nil {
+		log.Infof("failed to get active counters of Industrial Counter Bricklet (uid=%s): %s", dev.UID, err)
+		active = [4]bool{true, true, true, true}
+	}
+
+	var counters [4]ValueDesc
+	for ch := uint8(0); ch < 4; ch++ {
+		c, ok := settings[strconv.Itoa(int(ch))]
+		counters[ch] = c.counter()
+		if !ok {
+			continue
+		}
+		active[ch] = c.active()
+		if err := d.SetCounterActive(ch, c.active()); err != nil {
+			log.Errorf("failed to set counter %d active (uid=%s): %s", ch, dev.UID, err)
+		}
+		edge := industrialCounterEdges[c.Edge] // rising if not set
+		if err := d.SetCounterConfiguration(ch, edge, industrial_counter_bricklet.CountDirectionUp,
+			industrial_counter_bricklet.DutyCyclePrescaler1, industrial_counter_bricklet.FrequencyIntegrationTime1024MS); err != nil {
+			log.Errorf("failed to configure counter %d (uid=%s): %s", ch, dev.UID, err)
+		}
+	}
+
+	counterID := d.RegisterAllCounterCallback(func(counter [4]int64) {
+		for ch := range counter {
+			if active[ch] {
+				b.Send(dev, int64(ch), counters[ch], float64(counter[ch]))
+			}
+		}
+	})
+	d.SetAllCounterCallbackConfiguration(b.CallbackPeriod, false)
+
+	signalID := d.RegisterAllSignalDataCallback(func(dutyCycle [4]uint16, _ [4]uint64, frequency [4]uint32, value [4]bool) {
+		for ch := range value {
+			b.Send(dev, int64(ch), industrialCounterFrequency, float64(frequency[ch]))
+			b.Send(dev, int64(ch), industrialCounterDutyCycle, float64(dutyCycle[ch]))
+			b.Send(dev, int64(ch), industrialCounterLevel, bool2Float(value[ch]))
+		}
+	})
+	d.SetAllSignalDataCallbackConfiguration(b.CallbackPeriod, false)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	for ch := range counters {
+		values := []ValueDesc{industrialCounterFrequency, industrialCounterDutyCycle, industrialCounterLevel}
+		if active[ch] {
+			values = append(values, counters[ch])
+		}
+		b.PublishHAConfig(ic, dev, int64(ch), values)
+	}
+
+	return []Register{
+		{
+			Deregister: d.DeregisterAllCounterCallback,
+			ID:         counterID,
+		},
+		{
+			Deregister: d.DeregisterAllSignalDataCallback,
+			ID:         signalID,
+		},
+	}, nil
+}
@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/industrial_digital_in_4_v2_bricklet"
+)
+
+func init() {
+	RegisterDriver(industrialDigitalIn4V2Bricklet{})
+}
+
+var (
+	industrialDigitalIn4V2Level = digitalInputLevel()
+	industrialDigitalIn4V2Edges = digitalInputEdges(4)
+)
+
+type industrialDigitalIn4V2Bricklet struct{}
+
+func (industrialDigitalIn4V2Bricklet) DeviceIdentifier() uint16 {
+	return industrial_digital_in_4_v2_bricklet.DeviceIdentifier
+}
+func (industrialDigitalIn4V2Bricklet) Name() string { return "industrial_digital_in_4_bricklet_v2" }
+func (industrialDigitalIn4V2Bricklet) MultiSensor() {}
+
+func (industrialDigitalIn4V2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{industrialDigitalIn4V2Level, industrialDigitalIn4V2Edges}
+}
+
+func (in industrialDigitalIn4V2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := industrial_digital_in_4_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Industrial Digital In 4 Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	inputs := []uint8{0, 1, 2, 3}
+
+	levelID := d.RegisterAllValueCallback(func(_ [4]bool, value [4]bool) {
+		b.sendLevels(dev, industrialDigitalIn4V2Level, inputs, value[:])
+	})
+	d.SetAllValueCallbackConfiguration(b.CallbackPeriod, false)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	for _, ch := range inputs {
+		b.PublishHAConfig(in, dev, int64(ch), in.Values())
+	}
+
+	return []Register{
+		{
+			Deregister: d.DeregisterAllValueCallback,
+			ID:         levelID,
+		},
+		b.PollEdgeCounts(dev, industrialDigitalIn4V2Edges, inputs, func(ch uint8) (uint32, error) {
+			return d.GetEdgeCount(ch, false)
+		}),
+	}, nil
+}
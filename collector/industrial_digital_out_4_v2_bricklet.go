@@ -0,0 +1,37 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/industrial_digital_out_4_v2_bricklet"
+)
+
+func init() {
+	RegisterDriver(industrialDigitalOut4V2Bricklet{})
+}
+
+type industrialDigitalOut4V2Bricklet struct{}
+
+func (industrialDigitalOut4V2Bricklet) DeviceIdentifier() uint16 {
+	return industrial_digital_out_4_v2_bricklet.DeviceIdentifier
+}
+func (industrialDigitalOut4V2Bricklet) Name() string { return "industrial_digital_out_4_bricklet_v2" }
+func (industrialDigitalOut4V2Bricklet) MultiSensor() {}
+
+func (industrialDigitalOut4V2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{outputState}
+}
+
+func (o industrialDigitalOut4V2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := industrial_digital_out_4_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Industrial Digital Out 4 Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return b.RegisterOutputs(o, dev, 4, func() ([]bool, error) {
+		value, err := d.GetValue()
+		return value[:], err
+	}, d.SetSelectedValue), nil
+}
@@ -0,0 +1,37 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/industrial_dual_relay_bricklet"
+)
+
+func init() {
+	RegisterDriver(industrialDualRelayBricklet{})
+}
+
+type industrialDualRelayBricklet struct{}
+
+func (industrialDualRelayBricklet) DeviceIdentifier() uint16 {
+	return industrial_dual_relay_bricklet.DeviceIdentifier
+}
+func (industrialDualRelayBricklet) Name() string { return "industrial_dual_relay_bricklet" }
+func (industrialDualRelayBricklet) MultiSensor() {}
+
+func (industrialDualRelayBricklet) Values() []ValueDesc {
+	return []ValueDesc{outputState}
+}
+
+func (r industrialDualRelayBricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := industrial_dual_relay_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Industrial Dual Relay Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return b.RegisterOutputs(r, dev, 2, func() ([]bool, error) {
+		channel0, channel1, err := d.GetValue()
+		return []bool{channel0, channel1}, err
+	}, d.SetSelectedValue), nil
+}
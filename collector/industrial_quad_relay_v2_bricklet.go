@@ -0,0 +1,37 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/industrial_quad_relay_v2_bricklet"
+)
+
+func init() {
+	RegisterDriver(industrialQuadRelayV2Bricklet{})
+}
+
+type industrialQuadRelayV2Bricklet struct{}
+
+func (industrialQuadRelayV2Bricklet) DeviceIdentifier() uint16 {
+	return industrial_quad_relay_v2_bricklet.DeviceIdentifier
+}
+func (industrialQuadRelayV2Bricklet) Name() string { return "industrial_quad_relay_bricklet_v2" }
+func (industrialQuadRelayV2Bricklet) MultiSensor() {}
+
+func (industrialQuadRelayV2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{outputState}
+}
+
+func (r industrialQuadRelayV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := industrial_quad_relay_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Industrial Quad Relay Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return b.RegisterOutputs(r, dev, 4, func() ([]bool, error) {
+		value, err := d.GetValue()
+		return value[:], err
+	}, d.SetSelectedValue), nil
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/io16_v2_bricklet"
+)
+
+func init() {
+	RegisterDriver(io16V2Bricklet{})
+}
+
+var (
+	io16V2Level = digitalInputLevel()
+	io16V2Edges = digitalInputEdges(16)
+)
+
+type io16V2Bricklet struct{}
+
+func (io16V2Bricklet) DeviceIdentifier() uint16 { return io16_v2_bricklet.DeviceIdentifier }
+func (io16V2Bricklet) Name() string             { return "io16_bricklet_v2" }
+func (io16V2Bricklet) MultiSensor()             {}
+
+func (io16V2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{io16V2Level, io16V2Edges}
+}
+
+func (io io16V2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := io16_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect IO-16 Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	// channels configured as output are not exported
+	edgeCount := func(ch uint8) (uint32, error) {
+		return d.GetEdgeCount(ch, false)
+	}
+	inputs := inputChannels(16, func(ch uint8) (rune, error) {
+		direction, _, err := d.GetConfiguration(ch)
+		return direction, err
+	}, edgeCount)
+
+	levelID := d.RegisterAllInputValueCallback(func(_ [16]bool, value [16]bool) {
+		b.sendLevels(dev, io16V2Level, inputs, value[:])
+	})
+	d.SetAllInputValueCallbackConfiguration(b.CallbackPeriod, false)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	for _, ch := range inputs {
+		b.PublishHAConfig(io, dev, int64(ch), io.Values())
+	}
+
+	return []Register{
+		{
+			Deregister: d.DeregisterAllInputValueCallback,
+			ID:         levelID,
+		},
+		b.PollEdgeCounts(dev, io16V2Edges, inputs, edgeCount),
+	}, nil
+}
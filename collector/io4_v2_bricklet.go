@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/io4_v2_bricklet"
+)
+
+func init() {
+	RegisterDriver(io4V2Bricklet{})
+}
+
+var (
+	io4V2Level = digitalInputLevel()
+	io4V2Edges = digitalInputEdges(4)
+)
+
+type io4V2Bricklet struct{}
+
+func (io4V2Bricklet) DeviceIdentifier() uint16 { return io4_v2_bricklet.DeviceIdentifier }
+func (io4V2Bricklet) Name() string             { return "io4_bricklet_v2" }
+func (io4V2Bricklet) MultiSensor()             {}
+
+func (io4V2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{io4V2Level, io4V2Edges}
+}
+
+func (io io4V2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := io4_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect IO-4 Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	// channels configured as output are not exported
+	edgeCount := func(ch uint8) (uint32, error) {
+		return d.GetEdgeCount(ch, false)
+	}
+	inputs := inputChannels(4, func(ch uint8) (rune, error) {
+		direction, _, err := d.GetConfiguration(ch)
+		return direction, err
+	}, edgeCount)
+
+	levelID := d.RegisterAllInputValueCallback(func(_ [4]bool, value [4]bool) {
+		b.sendLevels(dev, io4V2Level, inputs, value[:])
+	})
+	d.SetAllInputValueCallbackConfiguration(b.CallbackPeriod, false)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	for _, ch := range inputs {
+		b.PublishHAConfig(io, dev, int64(ch), io.Values())
+	}
+
+	return []Register{
+		{
+			Deregister: d.DeregisterAllInputValueCallback,
+			ID:         levelID,
+		},
+		b.PollEdgeCounts(dev, io4V2Edges, inputs, edgeCount),
+	}, nil
+}
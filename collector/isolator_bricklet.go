@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/isolator_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(isolatorBricklet{})
+}
+
+var (
+	isolatorMessagesFromBrick = ValueDesc{
+		Index:      0,
+		Name:       "isolator_messages_from_brick",
+		Help:       "Messages passed by the Isolator Bricklet from the brick to the bricklet behind it",
+		Type:       prometheus.CounterValue,
+		HAType:     "sensor",
+		StateClass: "total_increasing",
+	}
+	isolatorMessagesFromBricklet = ValueDesc{
+		Index:      1,
+		Name:       "isolator_messages_from_bricklet",
+		Help:       "Messages passed by the Isolator Bricklet from the bricklet behind it to the brick",
+		Type:       prometheus.CounterValue,
+		HAType:     "sensor",
+		StateClass: "total_increasing",
+	}
+	isolatorConnectedBricklet = ValueDesc{
+		Index:  2,
+		Name:   "isolator_connected_bricklet",
+		Help:   "Device identifier of the bricklet behind the Isolator Bricklet, 0 if none, its UID is given in the connected_uid label",
+		Type:   prometheus.GaugeValue,
+		Labels: []string{"connected_uid"},
+	}
+)
+
+type isolatorBricklet struct{}
+
+func (isolatorBricklet) DeviceIdentifier() uint16 { return isolator_bricklet.DeviceIdentifier }
+func (isolatorBricklet) Name() string             { return "isolator_bricklet" }
+
+func (isolatorBricklet) Values() []ValueDesc {
+	return []ValueDesc{isolatorMessagesFromBrick, isolatorMessagesFromBricklet, isolatorConnectedBricklet}
+}
+
+func (isolatorBricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := isolator_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Isolator Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	statisticsID := d.RegisterStatisticsCallback(func(fromBrick uint32, fromBricklet uint32, deviceID uint16, uid string) {
+		b.Send(dev, 0, isolatorMessagesFromBrick, float64(fromBrick))
+		b.Send(dev, 0, isolatorMessagesFromBricklet, float64(fromBricklet))
+		b.SendLabels(dev, 0, isolatorConnectedBricklet, float64(deviceID), map[string]string{"connected_uid": uid})
+	})
+	d.SetStatisticsCallbackConfiguration(b.CallbackPeriod, false)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterStatisticsCallback,
+			ID:         statisticsID,
+		},
+	}, nil
+}
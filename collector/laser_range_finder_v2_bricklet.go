@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/laser_range_finder_v2_bricklet"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(laserRangeFinderV2Bricklet{})
+}
+
+type laserRangeFinderV2Bricklet struct{}
+
+func (laserRangeFinderV2Bricklet) DeviceIdentifier() uint16 {
+	return laser_range_finder_v2_bricklet.DeviceIdentifier
+}
+func (laserRangeFinderV2Bricklet) Name() string { return "laser_range_finder_bricklet_v2" }
+
+func (laserRangeFinderV2Bricklet) Values() []ValueDesc { return distanceValues() }
+
+func (laserRangeFinderV2Bricklet) HAValues(b *BrickdCollector, dev *Device) []ValueDesc {
+	return b.tankValues(dev)
+}
+
+func (laserRangeFinderV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := laser_range_finder_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Laser Range Finder Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	// the laser is off after power up
+	if err := d.SetEnable(true); err != nil {
+		log.Errorf("failed to enable laser of Laser Range Finder Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	distID := d.RegisterDistanceCallback(func(distance int16) {
+		b.sendDistance(dev, float64(distance)/100) // cm
+	})
+	d.SetDistanceCallbackConfiguration(b.CallbackPeriod, false, 'x', 0, 0)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterDistanceCallback,
+			ID:         distID,
+		},
+	}, nil
+}
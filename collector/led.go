@@ -0,0 +1,100 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/humidity_v2_bricklet"
+	log "github.com/sirupsen/logrus"
+)
+
+// StatusLEDConfigs maps the `led_status` config values to the status LED config of the devices.
+// The values are the same for all bricks and bricklets with a SetStatusLEDConfig() function.
+var StatusLEDConfigs = map[string]uint8{
+	"off":       humidity_v2_bricklet.StatusLEDConfigOff,
+	"on":        humidity_v2_bricklet.StatusLEDConfigOn,
+	"heartbeat": humidity_v2_bricklet.StatusLEDConfigShowHeartbeat,
+	"status":    humidity_v2_bricklet.StatusLEDConfigShowStatus,
+}
+
+var (
+	ErrUnknownDevice = errors.New("unknown device")
+	ErrNoStatusLED   = errors.New("device has no configurable status LED")
+)
+
+// ValidLEDStatus returns an error if status is not a valid `led_status` value, an empty
+// status is valid and leaves the LEDs untouched
+func ValidLEDStatus(status string) error {
+	if _, ok := StatusLEDConfigs[status]; !ok && status != "" {
+		return fmt.Errorf("invalid LED status %q, must be one of \"on\", \"off\", \"heartbeat\" or \"status\"", status)
+	}
+	return nil
+}
+
+// ledStatus returns the configured LED status of the device with the given uid
+func (b *BrickdCollector) ledStatus(uid string) string {
+	if status, ok := b.ledOverrides[uid]; ok {
+		return status
+	}
+	if status, ok := b.DeviceLEDStatus[uid]; ok {
+		return status
+	}
+	return b.LEDStatus
+}
+
+// setStatusLED sets the status LED of the device as configured. set is the SetStatusLEDConfig
+// function of the device, it is remembered to change the LED at runtime with SetLEDStatus.
+func (b *BrickdCollector) setStatusLED(dev *Device, set func(uint8) error) {
+	dev.statusLED = set
+	b.RLock()
+	status := b.ledStatus(dev.UID)
+	b.RUnlock()
+	if status == "" {
+		return
+	}
+	if err := set(StatusLEDConfigs[status]); err != nil {
+		log.Errorf("failed to set LED status for device %s: %s", dev.UID, err)
+	}
+}
+
+// DeviceLED returns the LED status of the device with the given uid, an empty string means
+// the LED has not been changed by the exporter
+func (b *BrickdCollector) DeviceLED(uid string) (string, error) {
+	b.RLock()
+	defer b.RUnlock()
+	dev, ok := b.Data.Devices[uid]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownDevice, uid)
+	}
+	if dev.statusLED == nil {
+		return "", fmt.Errorf("%w: %s (uid=%s)", ErrNoStatusLED, DeviceName(dev.DeviceID), uid)
+	}
+	return b.ledStatus(uid), nil
+}
+
+// SetLEDStatus changes the status LED of the device with the given uid. The new status
+// is kept when the device is registered again, e.g. after a reconnect.
+func (b *BrickdCollector) SetLEDStatus(uid, status string) error {
+	if err := ValidLEDStatus(status); err != nil {
+		return err
+	}
+	b.Lock()
+	defer b.Unlock()
+	dev, ok := b.Data.Devices[uid]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownDevice, uid)
+	}
+	if dev.statusLED == nil {
+		return fmt.Errorf("%w: %s (uid=%s)", ErrNoStatusLED, DeviceName(dev.DeviceID), uid)
+	}
+	if status != "" {
+		if err := dev.statusLED(StatusLEDConfigs[status]); err != nil {
+			return fmt.Errorf("failed to set LED status for device %s: %w", uid, err)
+		}
+	}
+	if b.ledOverrides == nil {
+		b.ledOverrides = make(map[string]string)
+	}
+	b.ledOverrides[uid] = status
+	return nil
+}
@@ -0,0 +1,193 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Tinkerforge/go-api-bindings/load_cell_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(loadCellV2Bricklet{})
+}
+
+var loadCellV2Weight = ValueDesc{
+	Index:       0,
+	Name:        "weight",
+	Help:        "Weight in kg minus the tare",
+	Type:        prometheus.GaugeValue,
+	Unit:        "kg",
+	Scale:       0.001,
+	HAType:      "sensor",
+	DeviceClass: "weight",
+}
+
+var (
+	ErrNoScale       = errors.New("device is no load cell")
+	ErrInvalidWeight = errors.New("invalid calibration weight")
+)
+
+// LoadCellSettings configure a Load Cell Bricklet 2.0
+type LoadCellSettings struct {
+	MovingAverage int     `yaml:"moving_average"` // length of the moving average, 1 - 100, 0 keeps the configuration of the bricklet
+	Tare          float64 `yaml:"tare"`           // subtracted from the weight in kg
+}
+
+// Validate returns an error if the moving average length is invalid
+func (s LoadCellSettings) Validate() error {
+	if s.MovingAverage < 0 || s.MovingAverage > 100 {
+		return fmt.Errorf("invalid moving_average %d, must be between 1 and 100", s.MovingAverage)
+	}
+	return nil
+}
+
+// scale is the load cell of a device, set by the driver to tare and calibrate it at runtime
+type scale struct {
+	weight    func() (int32, error) // GetWeight of the device in g
+	calibrate func(uint32) error    // Calibrate of the device with a weight in g
+}
+
+// tare returns the tare of the load cell with the given uid in kg, b must be locked
+func (b *BrickdCollector) tare(uid string) float64 {
+	if tare, ok := b.tareOverrides[uid]; ok {
+		return tare
+	}
+	return b.LoadCells[uid].Tare
+}
+
+// Tare sets the current weight of the load cell with the given uid as tare and returns it in
+// kg. The tare is saved with the function set by SetTareSaver and only used after it was
+// saved, so a tare which can not be saved is not used. It is kept until the config is
+// reloaded with a different tare for the device.
+func (b *BrickdCollector) Tare(uid string) (float64, error) {
+	sc, err := b.scale(uid)
+	if err != nil {
+		return 0, err
+	}
+	weight, err := sc.weight()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get weight of device %s: %w", uid, err)
+	}
+	tare := float64(weight) / 1000
+
+	b.RLock()
+	save := b.saveTare
+	b.RUnlock()
+	if save == nil {
+		log.Warnf("tare of load cell %s is not saved, set collector.load_cells.%s.tare to keep it after a restart", uid, uid)
+	} else if err := save(uid, tare); err != nil {
+		return 0, fmt.Errorf("failed to save tare of device %s: %w", uid, err)
+	}
+
+	b.Lock()
+	if b.tareOverrides == nil {
+		b.tareOverrides = make(map[string]float64)
+	}
+	b.tareOverrides[uid] = tare
+	b.Unlock()
+	log.Infof("tared load cell %s with %g kg", uid, tare)
+	return tare, nil
+}
+
+// SetTareSaver sets the function which saves a tare set with Tare, e.g. in the config file
+func (b *BrickdCollector) SetTareSaver(save func(uid string, tare float64) error) {
+	b.Lock()
+	defer b.Unlock()
+	b.saveTare = save
+}
+
+// Calibrate calibrates the load cell with the given uid with a known weight in kg: first with
+// 0 on the empty scale, then with the known weight on the scale. The calibration is saved in
+// the flash of the bricklet.
+func (b *BrickdCollector) Calibrate(uid string, weight float64) error {
+	if weight < 0 || weight*1000 > math.MaxUint32 {
+		return fmt.Errorf("%w: %g kg", ErrInvalidWeight, weight)
+	}
+	sc, err := b.scale(uid)
+	if err != nil {
+		return err
+	}
+	if err := sc.calibrate(uint32(math.Round(weight * 1000))); err != nil {
+		return fmt.Errorf("failed to calibrate device %s: %w", uid, err)
+	}
+	log.Infof("calibrated load cell %s with %g kg", uid, weight)
+	return nil
+}
+
+// scale returns the load cell of the device with the given uid
+func (b *BrickdCollector) scale(uid string) (*scale, error) {
+	b.RLock()
+	defer b.RUnlock()
+	dev, ok := b.Data.Devices[uid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDevice, uid)
+	}
+	if dev.scale == nil {
+		return nil, fmt.Errorf("%w: %s (uid=%s)", ErrNoScale, DeviceName(dev.DeviceID), uid)
+	}
+	return dev.scale, nil
+}
+
+type loadCellV2Bricklet struct{}
+
+func (loadCellV2Bricklet) DeviceIdentifier() uint16 { return load_cell_v2_bricklet.DeviceIdentifier }
+func (loadCellV2Bricklet) Name() string             { return "load_cell_bricklet_v2" }
+
+func (loadCellV2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{loadCellV2Weight}
+}
+
+func (loadCellV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := load_cell_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Load Cell Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	b.RLock()
+	s := b.LoadCells[dev.UID]
+	topic := b.SensorTopic(dev, 0)
+	b.RUnlock()
+	if s.MovingAverage != 0 {
+		if err := d.SetMovingAverage(uint16(s.MovingAverage)); err != nil {
+			log.Errorf("failed to set moving average of Load Cell Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+		}
+	}
+
+	weightID := d.RegisterWeightCallback(func(weight int32) {
+		b.RLock()
+		tare := b.tare(dev.UID)
+		b.RUnlock()
+		b.Send(dev, 0, loadCellV2Weight, float64(weight)-tare*1000)
+	})
+	d.SetWeightCallbackConfiguration(b.CallbackPeriod, false, 'x', 0, 0)
+
+	dev.scale = &scale{weight: d.GetWeight, calibrate: d.Calibrate}
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterWeightCallback,
+			ID:         weightID,
+		},
+		b.Subscribe(topic+"/tare", func([]byte) {
+			if _, err := b.Tare(dev.UID); err != nil {
+				log.Errorf("failed to tare Load Cell Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+			}
+		}),
+		b.Subscribe(topic+"/calibrate", func(payload []byte) {
+			weight, err := strconv.ParseFloat(strings.TrimSpace(string(payload)), 64)
+			if err != nil {
+				log.Warnf("invalid calibration weight for Load Cell Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+				return
+			}
+			if err := b.Calibrate(dev.UID, weight); err != nil {
+				log.Errorf("failed to calibrate Load Cell Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+			}
+		}),
+	}, nil
+}
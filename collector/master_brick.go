@@ -0,0 +1,140 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/master_brick"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(masterBrick{})
+}
+
+var (
+	masterStackCurrent = ValueDesc{
+		Index: 0,
+		Name:  "stack_current",
+		Help:  "Current of the stack in A",
+		Type:  prometheus.GaugeValue,
+		Unit:  "A",
+		Scale: 0.001,
+	}
+	masterStackVoltage = ValueDesc{
+		Index: 1,
+		Name:  "stack_voltage",
+		Help:  "Voltage of the stack in V",
+		Type:  prometheus.GaugeValue,
+		Unit:  "V",
+		Scale: 0.001,
+	}
+	masterUSBVoltage = ValueDesc{
+		Index: 2,
+		Name:  "usb_voltage",
+		Help:  "USB Voltage of the stack in V",
+		Type:  prometheus.GaugeValue,
+		Unit:  "V",
+		Scale: 0.001,
+	}
+	masterEthernetReceived = ValueDesc{
+		Index: 3,
+		Name:  "ethernet_received",
+		Help:  "Received bytes by Ethernet Extension",
+		Type:  prometheus.CounterValue,
+	}
+	masterEthernetTransmitted = ValueDesc{
+		Index: 4,
+		Name:  "ethernet_transmitted",
+		Help:  "Transmitted bytes by Ethernet Extension",
+		Type:  prometheus.CounterValue,
+	}
+)
+
+type masterBrick struct{}
+
+func (masterBrick) DeviceIdentifier() uint16 { return master_brick.DeviceIdentifier }
+func (masterBrick) Name() string             { return "master_brick" }
+
+func (masterBrick) Values() []ValueDesc {
+	return []ValueDesc{
+		masterStackCurrent,
+		masterStackVoltage,
+		masterUSBVoltage,
+		masterEthernetReceived,
+		masterEthernetTransmitted,
+	}
+}
+
+func (masterBrick) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	m, err := master_brick.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Master Brick (uid=%s): %s", dev.UID, err)
+	}
+
+	var regs []Register
+	hasEthernet, err := m.IsEthernetPresent()
+	if err != nil {
+		hasEthernet = false
+	}
+	if hasEthernet {
+		log.Debugf("ethernet extension is present")
+		regs = append(regs, b.PollEthernetState(&m, dev))
+	}
+
+	currID := m.RegisterStackCurrentCallback(func(current uint16) {
+		b.Send(dev, 0, masterStackCurrent, float64(current))
+	})
+	m.SetStackCurrentCallbackPeriod(b.CallbackPeriod)
+
+	voltID := m.RegisterStackVoltageCallback(func(voltage uint16) {
+		b.Send(dev, 0, masterStackVoltage, float64(voltage))
+	})
+	m.SetStackVoltageCallbackPeriod(b.CallbackPeriod)
+
+	usbVID := m.RegisterUSBVoltageCallback(func(voltage uint16) {
+		b.Send(dev, 0, masterUSBVoltage, float64(voltage))
+	})
+	m.SetUSBVoltageCallbackPeriod(b.CallbackPeriod)
+
+	// the Master Brick can only switch its status LED on or off
+	b.setStatusLED(dev, func(config uint8) error {
+		switch config {
+		case StatusLEDConfigs["on"], StatusLEDConfigs["status"]:
+			return m.EnableStatusLED()
+		case StatusLEDConfigs["off"]:
+			return m.DisableStatusLED()
+		}
+		return fmt.Errorf("%w: Master Brick does not support \"heartbeat\"", ErrNoStatusLED)
+	})
+
+	return append(regs,
+		Register{
+			Deregister: m.DeregisterStackCurrentCallback,
+			ID:         currID,
+		},
+		Register{
+			Deregister: m.DeregisterStackVoltageCallback,
+			ID:         voltID,
+		},
+		Register{
+			Deregister: m.DeregisterUSBVoltageCallback,
+			ID:         usbVID,
+		},
+	), nil
+}
+
+// PollEthernetState sends the Ethernet statistics every callback period, the returned
+// Register stops it
+func (b *BrickdCollector) PollEthernetState(m *master_brick.MasterBrick, dev *Device) Register {
+	return b.poll(make(chan struct{}), func() {
+		_, _, _, _, rxCount, txCount, _, err := m.GetEthernetStatus()
+		if err != nil {
+			log.Infof("failed to get ethernet status: %s", err)
+			return
+		}
+		log.Debugf("ethernet connected: rx %d / tx %d", rxCount, txCount)
+		b.Send(dev, 0, masterEthernetReceived, float64(rxCount))
+		b.Send(dev, 0, masterEthernetTransmitted, float64(txCount))
+	})
+}
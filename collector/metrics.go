@@ -0,0 +1,180 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metric declares a value exported by the devices. The exported metric name is
+// "brickd_<Name>_value" for gauges and "brickd_<Name>_total" for counters unless FullName
+// is set.
+type Metric struct {
+	Name     string
+	Help     string
+	Type     prometheus.ValueType
+	FullName string // full prometheus metric name, e.g. for metrics with a unit suffix
+}
+
+// FQName returns the full prometheus metric name
+func (m Metric) FQName() string {
+	if m.FullName != "" {
+		return m.FullName
+	}
+	switch m.Type {
+	case prometheus.CounterValue:
+		return "brickd_" + m.Name + "_total"
+	default:
+		return "brickd_" + m.Name + "_value"
+	}
+}
+
+// baseLabels are set on every value metric, additional labels from the `labels` and
+// `sensor_labels` config are appended to these
+var baseLabels = []string{"brickd", "uid", "id", "type", "sub_id", "sensor_id"}
+
+// ReservedLabels returns the label names which can not be used in the `labels` and
+// `sensor_labels` config: the baseLabels, the "metric" label of
+// brickd_value_last_received_timestamp_seconds and the labels of the values
+func ReservedLabels() []string {
+	labels := append([]string{"metric"}, baseLabels...)
+	return append(labels, valueLabelNames()...)
+}
+
+// descriptors holds the prometheus descriptors of all exported metrics
+type descriptors struct {
+	labels          []string
+	values          map[string]*prometheus.Desc
+	metrics         map[string]Metric
+	received        *prometheus.Desc
+	connections     *prometheus.Desc
+	up              *prometheus.Desc
+	connectionState *prometheus.Desc
+	disconnects     *prometheus.Desc
+	authFailures    *prometheus.Desc
+	lastConnect     *prometheus.Desc
+
+	deviceInfo           *prometheus.Desc
+	deviceLastEnumerated *prometheus.Desc
+}
+
+// newDescriptors creates the descriptors for the given metric catalogue. All value metrics
+// share the same variable labels: the baseLabels followed by the sorted extraLabels.
+func newDescriptors(metrics []Metric, extraLabels []string) (*descriptors, error) {
+	labels := append([]string{}, baseLabels...)
+	extra := make(map[string]bool)
+	for _, l := range baseLabels {
+		extra[l] = true
+	}
+	var names []string
+	for _, l := range extraLabels {
+		if extra[l] {
+			continue
+		}
+		extra[l] = true
+		names = append(names, l)
+	}
+	sort.Strings(names)
+	labels = append(labels, names...)
+
+	d := &descriptors{
+		labels:  labels,
+		values:  make(map[string]*prometheus.Desc),
+		metrics: make(map[string]Metric),
+		received: prometheus.NewDesc(
+			"brickd_value_last_received_timestamp_seconds",
+			"Time when the value of the metric has been received from brickd",
+			append(append([]string{}, labels...), "metric"),
+			nil,
+		),
+		connections: prometheus.NewDesc(
+			"brickd_connections_total",
+			"Number of connections to brickd",
+			[]string{"brickd"},
+			nil,
+		),
+		up: prometheus.NewDesc(
+			"brickd_up",
+			"Whether the connection to brickd is established (1) or not (0)",
+			[]string{"brickd"},
+			nil,
+		),
+		connectionState: prometheus.NewDesc(
+			"brickd_connection_state",
+			"State of the connection to brickd, 1 for the current state",
+			[]string{"brickd", "state"},
+			nil,
+		),
+		disconnects: prometheus.NewDesc(
+			"brickd_disconnects_total",
+			"Number of disconnects from brickd by reason",
+			[]string{"brickd", "reason"},
+			nil,
+		),
+		authFailures: prometheus.NewDesc(
+			"brickd_authentication_failures_total",
+			"Number of failed authentications with brickd",
+			[]string{"brickd"},
+			nil,
+		),
+		lastConnect: prometheus.NewDesc(
+			"brickd_last_connect_timestamp_seconds",
+			"Time of the last successful connection to brickd",
+			[]string{"brickd"},
+			nil,
+		),
+		deviceInfo: prometheus.NewDesc(
+			"brickd_device_info",
+			"Information about a discovered brick or bricklet, always 1",
+			[]string{"brickd", "uid", "id", "type", "connected_uid", "position", "hardware_version", "firmware_version", "available", "supported"},
+			nil,
+		),
+		deviceLastEnumerated: prometheus.NewDesc(
+			"brickd_device_last_enumerated_timestamp_seconds",
+			"Time when the brick or bricklet was last seen in an enumeration",
+			[]string{"brickd", "uid"},
+			nil,
+		),
+	}
+	declared := make(map[string]Metric)
+	for _, m := range metrics {
+		if prev, ok := declared[m.Name]; ok {
+			if prev != m {
+				return nil, fmt.Errorf("metric %q declared with conflicting help or type: %q (%s) vs. %q (%s)",
+					m.Name, prev.Help, prev.FQName(), m.Help, m.FQName())
+			}
+			continue
+		}
+		declared[m.Name] = m
+		d.values[m.Name] = prometheus.NewDesc(m.FQName(), m.Help, labels, nil)
+		d.metrics[m.Name] = m
+	}
+	return d, nil
+}
+
+// describe sends all descriptors to ch
+func (d *descriptors) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.connections
+	ch <- d.up
+	ch <- d.connectionState
+	ch <- d.disconnects
+	ch <- d.authFailures
+	ch <- d.lastConnect
+	ch <- d.received
+	ch <- d.deviceInfo
+	ch <- d.deviceLastEnumerated
+	for _, desc := range d.values {
+		ch <- desc
+	}
+}
+
+// labelValues returns the values of the variable labels in the order of the descriptors,
+// labels not present are exported as empty string, i.e. they're not shown
+func (d *descriptors) labelValues(labels map[string]string) []string {
+	values := make([]string, len(d.labels))
+	for i, l := range d.labels {
+		values[i] = labels[l]
+	}
+	return values
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/motion_detector_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(motionDetectorV2Bricklet{})
+}
+
+var (
+	motionDetectorV2Motion = ValueDesc{
+		Index:       0,
+		Name:        "motion",
+		Help:        "Motion detected (1) or the detection cycle ended (0)",
+		Type:        prometheus.GaugeValue,
+		HAType:      "binary_sensor",
+		DeviceClass: "motion",
+	}
+	motionDetectorV2Events = eventCount(1, "motion_events", "Number of motions detected")
+)
+
+type motionDetectorV2Bricklet struct{}
+
+func (motionDetectorV2Bricklet) DeviceIdentifier() uint16 {
+	return motion_detector_v2_bricklet.DeviceIdentifier
+}
+func (motionDetectorV2Bricklet) Name() string { return "motion_detector_bricklet_v2" }
+
+func (motionDetectorV2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{motionDetectorV2Motion, motionDetectorV2Events}
+}
+
+func (motionDetectorV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := motion_detector_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Motion Detector Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	detectedID := d.RegisterMotionDetectedCallback(func() {
+		b.SendEvent(dev, 0, motionDetectorV2Motion, 1, motionDetectorV2Events)
+	})
+	endedID := d.RegisterDetectionCycleEndedCallback(func() {
+		b.SendState(dev, 0, motionDetectorV2Motion, 0)
+	})
+
+	sendMotion := func() {
+		if motion, err := d.GetMotionDetected(); err != nil {
+			log.Infof("failed to get motion of Motion Detector Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+		} else {
+			b.SendState(dev, 0, motionDetectorV2Motion, float64(motion))
+		}
+	}
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterMotionDetectedCallback,
+			ID:         detectedID,
+		},
+		{
+			Deregister: d.DeregisterDetectionCycleEndedCallback,
+			ID:         endedID,
+		},
+		b.repeatEvents(dev, 0, motionDetectorV2Events, sendMotion),
+	}, nil
+}
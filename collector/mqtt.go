@@ -4,18 +4,61 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Tinkerforge/go-api-bindings/ipconnection"
 	log "github.com/sirupsen/logrus"
+	"github.com/vetinari/brickd_exporter/mqtt"
 )
 
-// Collect is part of the prometheus.Collector interface
-func (b *BrickdCollector) ExportMQTT(interval time.Duration) {
+// ExportMQTT publishes the values every interval until stop or the collector is closed
+func (b *BrickdCollector) ExportMQTT(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
 		b.exportMQTTOnce()
-		time.Sleep(interval)
+		select {
+		case <-stop:
+			return
+		case <-b.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *BrickdCollector) exportMQTTOnce() {
+	b.RLock()
+	mq := b.MQTT
+	b.RUnlock()
+	for _, msg := range b.mqttMessages() {
+		go mq.Client.Publish(mq.Topic.Name(msg.Topic), msg.Payload)
+	}
+}
+
+// SetMQTT replaces the MQTT config, e.g. after the topic or broker changed. The export
+// is restarted with the new config.
+func (b *BrickdCollector) SetMQTT(mq *mqtt.MQTT) {
+	b.Lock()
+	defer b.Unlock()
+	old := b.MQTT
+	b.MQTT = mq
+	if old != mq && !sameSubscriptions(old, mq) {
+		for topic := range b.commands {
+			go b.syncSubscription(old, topic)
+			go b.syncSubscription(mq, topic)
+		}
+	}
+	if b.mqttStop != nil {
+		close(b.mqttStop)
+		b.mqttStop = nil
+	}
+	if mq.Enabled && mq.Client != nil {
+		b.mqttStop = make(chan struct{})
+		go b.ExportMQTT(time.Duration(b.CallbackPeriod)*time.Millisecond, b.mqttStop)
 	}
 }
 
@@ -25,26 +68,70 @@ type mqttData struct {
 	Data   map[string]interface{}
 }
 
-func (b *BrickdCollector) exportMQTTOnce() {
+type mqttMessage struct {
+	Topic   string // without the mqtt.topic prefix
+	Payload []byte
+}
+
+// mqttMessages returns the JSON encoded status and current values to publish
+func (b *BrickdCollector) mqttMessages() []mqttMessage {
 	b.RLock()
 	defer b.RUnlock()
 
+	var msgs []mqttMessage
 	data := map[string]interface{}{
 		"connections_total": float64(b.ConnectCounter),
+		"up":                bool2Float(b.Connection.GetConnectionState() == ipconnection.ConnectionStateConnected),
 		"labels":            map[string]string{"brickd": b.Data.Address},
 	}
 
 	enc, err := json.Marshal(data)
 	if err != nil {
 		log.Errorf("failed to marshal json: %s", err)
+		return msgs
+	}
+	msgs = append(msgs, mqttMessage{"brickd_exporter", enc})
+	return append(msgs, b.valueMessages("", 0)...)
+}
+
+// publishSensor publishes the MQTT message of a sensor right away, e.g. after an event
+func (b *BrickdCollector) publishSensor(uid string, sensorID int64) {
+	b.RLock()
+	mq := b.MQTT
+	msgs := b.valueMessages(uid, sensorID)
+	b.RUnlock()
+	if mq == nil || !mq.Enabled || mq.Client == nil {
 		return
 	}
-	go b.MQTT.Client.Publish(b.MQTT.Topic.Name("brickd_exporter"), enc)
+	for _, msg := range msgs {
+		go mq.Client.Publish(mq.Topic.Name(msg.Topic), msg.Payload)
+	}
+}
 
-	mqData := make(map[string]mqttData)
+// valueMessages returns the JSON encoded values of the sensor with the given uid and sensor
+// id, of all sensors when uid is empty. b must be (read) locked.
+func (b *BrickdCollector) valueMessages(uid string, sensorID int64) []mqttMessage {
+	var msgs []mqttMessage
+
+	// several values of a sensor with the same name, e.g. the bins of a spectrum, are
+	// published as array ordered by their index
+	count := make(map[string]int)
 	for _, vals := range b.Data.Values {
 		for _, v := range vals {
-			if v.UID == "" || b.ignored(v.UID) {
+			count[fmt.Sprintf("%s.%d.%s", v.UID, v.SensorID, v.Name)]++
+		}
+	}
+
+	mqData := make(map[string]mqttData)
+	for _, vals := range b.Data.Values {
+		indexes := make([]int64, 0, len(vals))
+		for i := range vals {
+			indexes = append(indexes, i)
+		}
+		sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+		for _, i := range indexes {
+			v := vals[i]
+			if v.UID == "" || b.ignored(v.UID) || uid != "" && (v.UID != uid || v.SensorID != sensorID) {
 				continue
 			}
 			dev := fmt.Sprintf("%s.%d", v.UID, v.SensorID)
@@ -55,8 +142,8 @@ func (b *BrickdCollector) exportMQTTOnce() {
 					"brickd":    b.Data.Address,
 					"id":        strconv.FormatInt(int64(v.DeviceID), 10),
 					"type":      DeviceName(v.DeviceID),
-					"sub_id":    strconv.Itoa(v.SensorID), // deprecated
-					"sensor_id": strconv.Itoa(v.SensorID),
+					"sub_id":    strconv.FormatInt(v.SensorID, 10), // deprecated
+					"sensor_id": strconv.FormatInt(v.SensorID, 10),
 				}
 				for k, v := range b.Labels {
 					if _, exists := labels[k]; exists {
@@ -64,12 +151,11 @@ func (b *BrickdCollector) exportMQTTOnce() {
 					}
 					labels[k] = v
 				}
-				md.Topic = v.Name
+				md.Topic = b.sensorTopic(v.DeviceID, v.UID, v.SensorID)
 				if sl, ok := b.SensorLabels[v.UID]; ok {
-					if l, ok := sl[strconv.Itoa(v.SensorID)]; ok {
+					if l, ok := sl[strconv.FormatInt(v.SensorID, 10)]; ok {
 						for k, val := range l {
 							if k == "mqtt_topic" {
-								md.Topic = val
 								continue
 							}
 							if _, exists := labels[k]; exists {
@@ -79,19 +165,19 @@ func (b *BrickdCollector) exportMQTTOnce() {
 						}
 					}
 				}
-				switch DeviceName(v.DeviceID) {
-				case "Master Brick":
-					md.Topic = "master_brick"
-				case "HAT Brick":
-					md.Topic = "hat_brick"
-				case "HAT Zero Brick":
-					md.Topic = "hat_zero_brick"
-				}
 
 				md.Labels = labels
 				md.Data = make(map[string]interface{})
 				mqData[dev] = md
 			}
+			if count[dev+"."+v.Name] > 1 {
+				arr, _ := mqData[dev].Data[v.Name].([]float64)
+				mqData[dev].Data[v.Name] = append(arr, v.Value)
+				continue
+			}
+			for k, val := range v.Labels {
+				mqData[dev].Labels[k] = val
+			}
 			mqData[dev].Data[v.Name] = v.Value
 		}
 	}
@@ -100,25 +186,134 @@ func (b *BrickdCollector) exportMQTTOnce() {
 		enc, err := json.Marshal(dev.Data)
 		if err != nil {
 			log.Errorf("failed to marshal json: %s", err)
-			return
+			return msgs
 		}
-		go b.MQTT.Client.Publish(b.MQTT.Topic.Name(dev.Topic), enc)
+		msgs = append(msgs, mqttMessage{dev.Topic, enc})
+	}
+	return msgs
+}
+
+// Subscribe calls fn with the commands sent to the topic (without the mqtt.topic prefix)
+// until the returned Register is deregistered. The subscription is moved to the new client
+// when the MQTT config changes, see SetMQTT.
+func (b *BrickdCollector) Subscribe(topic string, fn func(payload []byte)) Register {
+	b.Lock()
+	b.commands[topic] = fn
+	mq := b.MQTT
+	b.Unlock()
+	b.syncSubscription(mq, topic)
+
+	return Register{
+		Deregister: func(uint64) { // called with b locked
+			delete(b.commands, topic)
+			go b.syncSubscription(b.MQTT, topic)
+		},
+	}
+}
+
+// syncSubscription subscribes to the topic on mq when a command handler is registered for it
+// and mq is the current MQTT config, and unsubscribes from it otherwise. The subscriptions are
+// changed one at a time with the handler registered at that time, so unsubscribing after a
+// Deregister can not drop a newer subscription of the same topic. b must not be locked, paho
+// waits for the running command handlers (which lock b) before acknowledging a subscription.
+func (b *BrickdCollector) syncSubscription(mq *mqtt.MQTT, topic string) {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	b.RLock()
+	fn, ok := b.commands[topic]
+	current := sameSubscriptions(b.MQTT, mq)
+	b.RUnlock()
+	if ok && current {
+		subscribe(mq, topic, fn)
+	} else {
+		unsubscribe(mq, topic)
+	}
+}
+
+// sameSubscriptions returns whether the subscriptions of a and b are the same, i.e. they use
+// the same client and topic prefix
+func sameSubscriptions(a, b *mqtt.MQTT) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Client == b.Client && a.Topic == b.Topic && a.Enabled == b.Enabled
+}
+
+// subscribe subscribes to the topic if MQTT is enabled
+func subscribe(mq *mqtt.MQTT, topic string, fn func([]byte)) {
+	if mq == nil || !mq.Enabled || mq.Client == nil {
+		return
+	}
+	log.Debugf("subscribing to %s", mq.Topic.Name(topic))
+	if err := mq.Client.Subscribe(mq.Topic.Name(topic), fn); err != nil {
+		log.Warnf("failed to subscribe to %s: %s", mq.Topic.Name(topic), err)
 	}
 }
 
-func (b *BrickdCollector) SensorTopic(dev *Device, index int) string {
+// unsubscribe unsubscribes from the topic if MQTT is enabled
+func unsubscribe(mq *mqtt.MQTT, topic string) {
+	if mq == nil || !mq.Enabled || mq.Client == nil {
+		return
+	}
+	if err := mq.Client.Unsubscribe(mq.Topic.Name(topic)); err != nil {
+		log.Debugf("failed to unsubscribe from %s: %s", mq.Topic.Name(topic), err)
+	}
+}
+
+// CommandTopic returns the topic (without the mqtt.topic prefix) on which commands for a
+// sensor (e.g. a relay channel) are received, "<mqtt_topic>/set" when the mqtt_topic is set
+// in the sensor_labels, "<DefaultTopic>/<sensor id>/set" otherwise. b must be (read) locked.
+func (b *BrickdCollector) CommandTopic(dev *Device, index int64) string {
 	if sl, ok := b.SensorLabels[dev.UID]; ok {
-		if l, ok := sl[strconv.Itoa(index)]; ok {
+		if l, ok := sl[strconv.FormatInt(index, 10)]; ok {
+			if t, ok := l["mqtt_topic"]; ok {
+				return t + "/set"
+			}
+		}
+	}
+	return b.DefaultTopic(dev) + "/" + strconv.FormatInt(index, 10) + "/set"
+}
+
+// SensorTopic returns the topic (without the mqtt.topic prefix) on which the values of a
+// sensor are published, see sensorTopic. b must be (read) locked.
+func (b *BrickdCollector) SensorTopic(dev *Device, index int64) string {
+	return b.sensorTopic(dev.DeviceID, dev.UID, index)
+}
+
+// sensorTopic returns the topic of a sensor: the mqtt_topic when it is set in the
+// sensor_labels, "<DefaultTopic>/<sensor id>" for the sensors of MultiSensorDriver devices
+// (like CommandTopic) and "<DefaultTopic>" for other devices. The bricks with a fixed topic
+// are published there. b must be (read) locked.
+func (b *BrickdCollector) sensorTopic(deviceID uint16, uid string, sensorID int64) string {
+	switch DeviceName(deviceID) {
+	case "Master Brick":
+		return "master_brick"
+	case "HAT Brick":
+		return "hat_brick"
+	case "HAT Zero Brick":
+		return "hat_zero_brick"
+	}
+	if sl, ok := b.SensorLabels[uid]; ok {
+		if l, ok := sl[strconv.FormatInt(sensorID, 10)]; ok {
 			if t, ok := l["mqtt_topic"]; ok {
 				return t
 			}
 		}
 	}
-	return b.DefaultTopic(dev)
+	topic := defaultTopic(deviceID, uid)
+	if _, ok := drivers[deviceID].(MultiSensorDriver); ok {
+		topic += "/" + strconv.FormatInt(sensorID, 10)
+	}
+	return topic
 }
 
 var cleanID = regexp.MustCompile(`[^a-z0-9_]`)
 
 func (b *BrickdCollector) DefaultTopic(dev *Device) string {
-	return cleanID.ReplaceAllString(strings.ToLower(DeviceName(dev.DeviceID)), "_") + "_" + dev.UID
+	return defaultTopic(dev.DeviceID, dev.UID)
+}
+
+// defaultTopic returns "<type>_<uid>", e.g. "industrial_quad_relay_bricklet_2_0_Gh1"
+func defaultTopic(deviceID uint16, uid string) string {
+	return cleanID.ReplaceAllString(strings.ToLower(DeviceName(deviceID)), "_") + "_" + uid
 }
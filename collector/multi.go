@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MultiCollector exports the values of several brickd daemons on a single /metrics endpoint.
+// Each BrickdCollector keeps its own connection, devices and values, so an unreachable
+// brickd does not block the others.
+type MultiCollector struct {
+	Collectors  []*BrickdCollector
+	AdminAPI    bool // enables tare and calibrate of the device API, see APIHandler
+	descriptors *descriptors
+}
+
+// NewMultiCollector creates a new prometheus.Collector for all given collectors. It fails
+// when the metric catalogue is inconsistent, i.e. a metric was declared with different help
+// texts or types, or a label name is invalid.
+func NewMultiCollector(collectors ...*BrickdCollector) (*MultiCollector, error) {
+	var settings []Settings
+	for _, c := range collectors {
+		c.RLock()
+		settings = append(settings, Settings{Labels: c.Labels, SensorLabels: c.SensorLabels, Counters: c.Counters})
+		c.RUnlock()
+	}
+	m, err := NewMultiCollectorFor(settings...)
+	if err != nil {
+		return nil, err
+	}
+	m.Collectors = collectors
+	return m, nil
+}
+
+// NewMultiCollectorFor creates a new prometheus.Collector for collectors with the given
+// settings like NewMultiCollector, but without the collectors. This checks a config before
+// it is applied, the collectors are set in Collectors once they are running.
+func NewMultiCollectorFor(settings ...Settings) (*MultiCollector, error) {
+	reserved := make(map[string]bool)
+	for _, l := range ReservedLabels() {
+		reserved[l] = true
+	}
+	labels := valueLabelNames()
+	metrics := Metrics()
+	for _, s := range settings {
+		names := labelNames(s.Labels, s.SensorLabels)
+		for _, l := range names {
+			if reserved[l] {
+				return nil, fmt.Errorf("label name %q is reserved", l)
+			}
+		}
+		labels = append(labels, names...)
+		metrics = append(metrics, counterMetrics(s.Counters)...)
+	}
+	d, err := newDescriptors(metrics, labels)
+	if err != nil {
+		return nil, err
+	}
+	m := &MultiCollector{descriptors: d}
+	// the descriptors only report invalid names when they are registered
+	if err := prometheus.NewRegistry().Register(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Describe is part of the prometheus.Collector interface
+func (m *MultiCollector) Describe(ch chan<- *prometheus.Desc) {
+	m.descriptors.describe(ch)
+}
+
+// Collect is part of the prometheus.Collector interface
+func (m *MultiCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.Collectors {
+		c.collect(ch, m.descriptors)
+	}
+}
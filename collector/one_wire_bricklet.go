@@ -0,0 +1,209 @@
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Tinkerforge/go-api-bindings/one_wire_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(oneWireBricklet{})
+}
+
+// the DS18B20 probes are the sensors of the bricklet, the sensor id is the ROM ID of the probe
+// without its CRC, see oneWireSensorID
+var (
+	oneWireTemperature = ValueDesc{
+		Index:       0,
+		Name:        "temperature",
+		Help:        "Temperature in °C",
+		Type:        prometheus.GaugeValue,
+		Unit:        "°C",
+		Scale:       0.0625,
+		HAType:      "sensor",
+		DeviceClass: "temperature",
+		Labels:      []string{"rom"},
+	}
+)
+
+// the 1-Wire commands of the DS18B20
+const (
+	ds18b20Family          = 0x28
+	ds18b20ConvertT        = 0x44
+	ds18b20ReadScratchpad  = 0xbe
+	ds18b20ConversionDelay = 750 * time.Millisecond // at the default 12 bit resolution
+)
+
+// oneWireSensorID returns the sensor id of a ROM ID, the family code and serial number
+func oneWireSensorID(rom uint64) int64 {
+	return int64(rom & 0x00ffffffffffffff)
+}
+
+// oneWireROM returns the ROM ID in the format of the Linux w1 driver, e.g. "28-0000075b1f5a"
+func oneWireROM(rom uint64) string {
+	return fmt.Sprintf("%02x-%012x", rom&0xff, rom>>8&0xffffffffffff)
+}
+
+// OneWireSensorID returns the sensor id of a probe from its ROM ID in the format of the
+// Linux w1 driver, e.g. "31593552424" for "28-0000075b1f5a"
+func OneWireSensorID(rom string) (string, error) {
+	if len(rom) != 15 || rom[2] != '-' {
+		return "", fmt.Errorf("invalid ROM ID %q", rom)
+	}
+	family, err := strconv.ParseUint(rom[:2], 16, 8)
+	if err != nil {
+		return "", fmt.Errorf("invalid family code in ROM ID %q", rom)
+	}
+	serial, err := strconv.ParseUint(rom[3:], 16, 48)
+	if err != nil {
+		return "", fmt.Errorf("invalid serial number in ROM ID %q", rom)
+	}
+	return strconv.FormatInt(oneWireSensorID(serial<<8|family), 10), nil
+}
+
+type oneWireBricklet struct{}
+
+func (oneWireBricklet) DeviceIdentifier() uint16 { return one_wire_bricklet.DeviceIdentifier }
+func (oneWireBricklet) Name() string             { return "one_wire_bricklet" }
+func (oneWireBricklet) MultiSensor()             {}
+
+func (oneWireBricklet) Values() []ValueDesc {
+	return []ValueDesc{oneWireTemperature}
+}
+
+func (ow oneWireBricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := one_wire_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect One Wire Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	stop := make(chan struct{})
+	known := make(map[uint64]bool) // ROM IDs with a published HA config
+	return []Register{
+		b.poll(stop, func() { ow.measure(b, &d, dev, known, stop) }),
+	}, nil
+}
+
+// measure searches the bus for DS18B20 probes, starts the temperature conversion of all
+// probes and reads their temperatures. The HomeAssistant config is published for new probes,
+// probes which are no longer found are removed.
+func (ow oneWireBricklet) measure(b *BrickdCollector, d *one_wire_bricklet.OneWireBricklet, dev *Device,
+	known map[uint64]bool, stop chan struct{}) {
+	roms, ok := ow.search(d, dev)
+	if !ok {
+		return
+	}
+	ow.update(b, dev, roms, known)
+	if len(roms) > 0 && ow.convert(d, dev, b.done, stop) {
+		for _, rom := range roms {
+			ow.read(b, d, dev, rom)
+		}
+	}
+}
+
+// search returns the ROM IDs of the DS18B20 probes on the bus, ok is false when the search
+// failed
+func (oneWireBricklet) search(d *one_wire_bricklet.OneWireBricklet, dev *Device) (roms []uint64, ok bool) {
+	ids, status, err := d.SearchBus()
+	switch {
+	case err != nil:
+		log.Infof("failed to search 1-Wire bus (uid=%s): %s", dev.UID, err)
+		return nil, false
+	case status == one_wire_bricklet.StatusNoPresence:
+		return nil, true
+	case status != one_wire_bricklet.StatusOK:
+		log.Infof("failed to search 1-Wire bus (uid=%s): status %d", dev.UID, status)
+		return nil, false
+	}
+	for _, id := range ids {
+		if id&0xff == ds18b20Family {
+			roms = append(roms, id)
+		}
+	}
+	return roms, true
+}
+
+// update publishes the HA config of new probes and removes the probes which are not found
+// anymore
+func (ow oneWireBricklet) update(b *BrickdCollector, dev *Device, roms []uint64, known map[uint64]bool) {
+	found := make(map[uint64]bool, len(roms))
+	for _, rom := range roms {
+		found[rom] = true
+		if !known[rom] {
+			log.Debugf("found DS18B20 %s (uid=%s)", oneWireROM(rom), dev.UID)
+			known[rom] = true
+			b.PublishHAConfig(ow, dev, oneWireSensorID(rom), ow.Values())
+		}
+	}
+	for rom := range known {
+		if !found[rom] {
+			log.Infof("removing DS18B20 %s (uid=%s), not found on the bus", oneWireROM(rom), dev.UID)
+			delete(known, rom)
+			b.RemoveSensor(ow, dev, oneWireSensorID(rom), ow.Values())
+		}
+	}
+}
+
+// convert starts the temperature conversion of all probes and waits until it is finished,
+// it returns false when the conversion failed or polling was stopped
+func (oneWireBricklet) convert(d *one_wire_bricklet.OneWireBricklet, dev *Device, done, stop chan struct{}) bool {
+	status, err := d.WriteCommand(0, ds18b20ConvertT) // skip ROM, i.e. all probes
+	if err != nil || status != one_wire_bricklet.StatusOK {
+		log.Infof("failed to start temperature conversion (uid=%s): status %d, %v", dev.UID, status, err)
+		return false
+	}
+	select {
+	case <-stop:
+		return false
+	case <-done:
+		return false
+	case <-time.After(ds18b20ConversionDelay):
+		return true
+	}
+}
+
+// read reads the scratchpad of a probe and sends its temperature
+func (oneWireBricklet) read(b *BrickdCollector, d *one_wire_bricklet.OneWireBricklet, dev *Device, rom uint64) {
+	status, err := d.WriteCommand(rom, ds18b20ReadScratchpad)
+	if err != nil || status != one_wire_bricklet.StatusOK {
+		log.Infof("failed to read DS18B20 %s (uid=%s): status %d, %v", oneWireROM(rom), dev.UID, status, err)
+		return
+	}
+	var scratchpad [9]byte
+	for i := range scratchpad {
+		data, status, err := d.Read()
+		if err != nil || status != one_wire_bricklet.StatusOK {
+			log.Infof("failed to read DS18B20 %s (uid=%s): status %d, %v", oneWireROM(rom), dev.UID, status, err)
+			return
+		}
+		scratchpad[i] = data
+	}
+	if crc8(scratchpad[:8]) != scratchpad[8] {
+		log.Infof("invalid CRC reading DS18B20 %s (uid=%s)", oneWireROM(rom), dev.UID)
+		return
+	}
+	raw := int16(uint16(scratchpad[0]) | uint16(scratchpad[1])<<8)
+	b.SendLabels(dev, oneWireSensorID(rom), oneWireTemperature, float64(raw), map[string]string{"rom": oneWireROM(rom)})
+}
+
+// crc8 returns the Dallas/Maxim 1-Wire CRC of data
+func crc8(data []byte) byte {
+	var crc byte
+	for _, v := range data {
+		for i := 0; i < 8; i++ {
+			mix := (crc ^ v) & 0x01
+			crc >>= 1
+			if mix != 0 {
+				crc ^= 0x8c
+			}
+			v >>= 1
+		}
+	}
+	return crc
+}
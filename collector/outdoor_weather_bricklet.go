@@ -0,0 +1,230 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Tinkerforge/go-api-bindings/outdoor_weather_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(outdoorWeatherBricklet{})
+}
+
+// OutdoorWeatherMaxAgeLimit is the largest usable OutdoorWeatherMaxAge: the bricklet reports the
+// time since a station or sensor was last received as uint16 seconds
+const OutdoorWeatherMaxAgeLimit = 65535 * time.Second
+
+// the values of the sensors and stations, the Index is relative to the sensor id, see
+// outdoorWeatherSensorID and outdoorWeatherStationID
+var (
+	outdoorWeatherTemperature = ValueDesc{
+		Index:       0,
+		Name:        "temperature",
+		Help:        "Temperature in °C",
+		Type:        prometheus.GaugeValue,
+		Unit:        "°C",
+		Scale:       0.1,
+		HAType:      "sensor",
+		DeviceClass: "temperature",
+	}
+	outdoorWeatherHumidity = ValueDesc{
+		Index:       1,
+		Name:        "humidity",
+		Help:        "Humidity of the air in %rH",
+		Type:        prometheus.GaugeValue,
+		Unit:        "%",
+		HAType:      "sensor",
+		DeviceClass: "humidity",
+	}
+	outdoorWeatherWindSpeed = ValueDesc{
+		Index:       2,
+		Name:        "wind_speed",
+		Help:        "WindSpeed in m/s",
+		Type:        prometheus.GaugeValue,
+		Unit:        "m/s",
+		Scale:       0.1,
+		HAType:      "sensor",
+		DeviceClass: "wind_speed",
+	}
+	outdoorWeatherGustSpeed = ValueDesc{
+		Index:       3,
+		Name:        "gust_speed",
+		Help:        "GustSpeed in m/s",
+		Type:        prometheus.GaugeValue,
+		Unit:        "m/s",
+		Scale:       0.1,
+		HAType:      "sensor",
+		DeviceClass: "wind_speed",
+	}
+	outdoorWeatherRain = ValueDesc{
+		Index:       4,
+		Name:        "rain",
+		Help:        "Rain in mm",
+		Type:        prometheus.CounterValue,
+		Unit:        "mm",
+		Scale:       0.1,
+		HAType:      "sensor",
+		DeviceClass: "precipitation",
+	}
+	outdoorWeatherWindDirection = ValueDesc{
+		Index: 5,
+		Name:  "wind_direction",
+		Help:  "Wind Direction",
+		Type:  prometheus.GaugeValue,
+	}
+	outdoorWeatherBatteryLow = ValueDesc{
+		Index:       6,
+		Name:        "battery_low",
+		Help:        "Battery Status Low",
+		Type:        prometheus.GaugeValue,
+		HAType:      "binary_sensor",
+		DeviceClass: "battery",
+	}
+	outdoorWeatherLastSeen = ValueDesc{
+		Index:      7,
+		Name:       "outdoor_weather_last_seen",
+		Help:       "Seconds since the outdoor weather station or sensor was last received",
+		Type:       prometheus.GaugeValue,
+		MetricName: "brickd_outdoor_weather_last_seen_seconds",
+	}
+)
+
+// outdoorWeatherSensorID returns the sensor id of a sensor identifier
+func outdoorWeatherSensorID(identifier uint8) int64 {
+	return int64(identifier) << 8
+}
+
+// outdoorWeatherStationID returns the sensor id of a station identifier
+func outdoorWeatherStationID(identifier uint8) int64 {
+	return int64(identifier)<<8 + 65536
+}
+
+type outdoorWeatherBricklet struct{}
+
+func (outdoorWeatherBricklet) DeviceIdentifier() uint16 {
+	return outdoor_weather_bricklet.DeviceIdentifier
+}
+func (outdoorWeatherBricklet) Name() string { return "outdoor_weather_bricklet" }
+func (outdoorWeatherBricklet) MultiSensor() {}
+
+func (outdoorWeatherBricklet) sensorValues() []ValueDesc {
+	return []ValueDesc{outdoorWeatherTemperature, outdoorWeatherHumidity, outdoorWeatherLastSeen}
+}
+
+func (outdoorWeatherBricklet) Values() []ValueDesc {
+	return []ValueDesc{
+		outdoorWeatherTemperature,
+		outdoorWeatherHumidity,
+		outdoorWeatherWindSpeed,
+		outdoorWeatherGustSpeed,
+		outdoorWeatherRain,
+		outdoorWeatherWindDirection,
+		outdoorWeatherBatteryLow,
+		outdoorWeatherLastSeen,
+	}
+}
+
+func (ow outdoorWeatherBricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := outdoor_weather_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Outdoor Weather Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	d.SetSensorCallbackConfiguration(true)
+	sensorID := d.RegisterSensorDataCallback(func(identifier uint8, temperature int16, humidity uint8) {
+		id := outdoorWeatherSensorID(identifier)
+		b.Send(dev, id, outdoorWeatherTemperature, float64(temperature))
+		b.Send(dev, id, outdoorWeatherHumidity, float64(humidity))
+	})
+
+	d.SetStationCallbackConfiguration(true)
+	stationID := d.RegisterStationDataCallback(func(identifier uint8, temperature int16, humidity uint8, windSpeed uint32, gustSpeed uint32, rain uint32, windDirection uint8, batteryLow bool) {
+		id := outdoorWeatherStationID(identifier)
+		b.Send(dev, id, outdoorWeatherTemperature, float64(temperature))
+		b.Send(dev, id, outdoorWeatherHumidity, float64(humidity))
+		b.Send(dev, id, outdoorWeatherWindSpeed, float64(windSpeed))
+		b.Send(dev, id, outdoorWeatherGustSpeed, float64(gustSpeed))
+		b.Send(dev, id, outdoorWeatherRain, float64(rain))
+		b.Send(dev, id, outdoorWeatherWindDirection, float64(windDirection))
+		b.Send(dev, id, outdoorWeatherBatteryLow, bool2Float(batteryLow))
+	})
+
+	known := make(map[int64]bool) // sensor ids with a published HA config
+	return []Register{
+		{
+			Deregister: d.DeregisterSensorDataCallback,
+			ID:         sensorID,
+		},
+		{
+			Deregister: d.DeregisterStationDataCallback,
+			ID:         stationID,
+		},
+		b.poll(make(chan struct{}), func() { ow.scan(b, &d, dev, known) }),
+	}, nil
+}
+
+// scan reads the identifiers of the stations and sensors. The HomeAssistant config is
+// published for new stations and sensors, the ones not seen for longer than
+// OutdoorWeatherMaxAge are removed.
+func (ow outdoorWeatherBricklet) scan(b *BrickdCollector, d *outdoor_weather_bricklet.OutdoorWeatherBricklet, dev *Device, known map[int64]bool) {
+	if sids, err := d.GetSensorIdentifiers(); err != nil {
+		log.Infof("failed to get sensor identifiers (uid=%s): %s", dev.UID, err)
+	} else {
+		for _, sid := range sids {
+			_, _, lastChange, err := d.GetSensorData(sid)
+			if err != nil {
+				log.Infof("failed to get data of sensor %d (uid=%s): %s", sid, dev.UID, err)
+				continue
+			}
+			ow.seen(b, dev, outdoorWeatherSensorID(sid), ow.sensorValues(), lastChange, known)
+		}
+	}
+
+	if stids, err := d.GetStationIdentifiers(); err != nil {
+		log.Infof("failed to get station identifiers (uid=%s): %s", dev.UID, err)
+	} else {
+		for _, stid := range stids {
+			_, _, _, _, _, _, _, lastChange, err := d.GetStationData(stid)
+			if err != nil {
+				log.Infof("failed to get data of station %d (uid=%s): %s", stid, dev.UID, err)
+				continue
+			}
+			ow.seen(b, dev, outdoorWeatherStationID(stid), ow.Values(), lastChange, known)
+		}
+	}
+}
+
+// seen handles a station or sensor found by scan which was last received lastChange seconds ago
+func (ow outdoorWeatherBricklet) seen(b *BrickdCollector, dev *Device, id int64, values []ValueDesc, lastChange uint16, known map[int64]bool) {
+	b.RLock()
+	maxAge := b.OutdoorWeatherMaxAge
+	b.RUnlock()
+
+	if maxAge != 0 && time.Duration(lastChange)*time.Second > maxAge {
+		if known[id] {
+			log.Infof("removing outdoor weather sensor %d (uid=%s), last seen %ds ago", id, dev.UID, lastChange)
+			delete(known, id)
+			b.RemoveSensor(ow, dev, id, values)
+		}
+		return
+	}
+
+	b.Send(dev, id, outdoorWeatherLastSeen, float64(lastChange))
+	if !known[id] {
+		log.Debugf("found outdoor weather sensor %d (uid=%s)", id, dev.UID)
+		known[id] = true
+		b.PublishHAConfig(ow, dev, id, values)
+	}
+}
+
+func bool2Float(v bool) float64 {
+	if v {
+		return 1.0
+	}
+	return 0.0
+}
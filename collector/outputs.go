@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// outputState is the state of a channel of the relay and output bricklets, the sensor id
+// is the channel
+var outputState = ValueDesc{
+	Index:  0,
+	Name:   "output",
+	Help:   "State of the relay or output channel, on (1) or off (0)",
+	Type:   prometheus.GaugeValue,
+	HAType: "switch",
+}
+
+// RegisterOutputs exports the state of the channels of a relay or output bricklet and
+// switches them on commands received via MQTT, see CommandTopic. The state is read with
+// get every callback period, set switches a channel. The returned registrations stop both.
+func (b *BrickdCollector) RegisterOutputs(drv MultiSensorDriver, dev *Device, channels int,
+	get func() ([]bool, error), set func(channel uint8, on bool) error) []Register {
+	var regs []Register
+	for ch := 0; ch < channels; ch++ {
+		b.RLock()
+		topic := b.CommandTopic(dev, int64(ch))
+		b.RUnlock()
+		regs = append(regs, b.Subscribe(topic, b.switchCommand(dev, uint8(ch), set)))
+		b.PublishHAConfig(drv, dev, int64(ch), []ValueDesc{outputState})
+	}
+
+	return append(regs, b.poll(make(chan struct{}), func() {
+		values, err := get()
+		if err != nil {
+			log.Infof("failed to get output state of %s (uid=%s): %s", DeviceName(dev.DeviceID), dev.UID, err)
+			return
+		}
+		for ch, on := range values {
+			b.Send(dev, int64(ch), outputState, bool2Float(on))
+		}
+	}))
+}
+
+// switchCommand returns the handler of the commands for a channel
+func (b *BrickdCollector) switchCommand(dev *Device, channel uint8, set func(channel uint8, on bool) error) func([]byte) {
+	return func(payload []byte) {
+		on, err := parseSwitchCommand(string(payload))
+		if err != nil {
+			log.Warnf("invalid command for channel %d of %s (uid=%s): %s", channel, DeviceName(dev.DeviceID), dev.UID, err)
+			return
+		}
+		if err := set(channel, on); err != nil {
+			log.Errorf("failed to switch channel %d of %s (uid=%s): %s", channel, DeviceName(dev.DeviceID), dev.UID, err)
+			return
+		}
+		log.Infof("switched channel %d of %s (uid=%s) %s", channel, DeviceName(dev.DeviceID), dev.UID, strings.ToLower(string(payload)))
+		b.Send(dev, int64(channel), outputState, bool2Float(on))
+	}
+}
+
+// parseSwitchCommand parses the payload of a command, "ON" and "OFF" as sent by
+// HomeAssistant, "1" and "0" or "true" and "false"
+func parseSwitchCommand(payload string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(payload)) {
+	case "on", "1", "true":
+		return true, nil
+	case "off", "0", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown command %q, must be ON or OFF", payload)
+}
@@ -0,0 +1,179 @@
+package collector
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/Tinkerforge/go-api-bindings/particulate_matter_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(particulateMatterBricklet{})
+}
+
+var (
+	particulateMatterPM1 = ValueDesc{
+		Index:       0,
+		Name:        "pm1",
+		Help:        "Concentration of particulate matter PM1.0 in µg/m³",
+		Type:        prometheus.GaugeValue,
+		Unit:        "µg/m³",
+		HAType:      "sensor",
+		DeviceClass: "pm1",
+	}
+	particulateMatterPM25 = ValueDesc{
+		Index:       1,
+		Name:        "pm25",
+		Help:        "Concentration of particulate matter PM2.5 in µg/m³",
+		Type:        prometheus.GaugeValue,
+		Unit:        "µg/m³",
+		HAType:      "sensor",
+		DeviceClass: "pm25",
+	}
+	particulateMatterPM10 = ValueDesc{
+		Index:       2,
+		Name:        "pm10",
+		Help:        "Concentration of particulate matter PM10 in µg/m³",
+		Type:        prometheus.GaugeValue,
+		Unit:        "µg/m³",
+		HAType:      "sensor",
+		DeviceClass: "pm10",
+	}
+	particulateMatterCount03 = ValueDesc{
+		Index: 3,
+		Name:  "particles_0_3um",
+		Help:  "Number of particles greater than 0.3µm in 100 ml of air",
+		Type:  prometheus.GaugeValue,
+	}
+	particulateMatterCount05 = ValueDesc{
+		Index: 4,
+		Name:  "particles_0_5um",
+		Help:  "Number of particles greater than 0.5µm in 100 ml of air",
+		Type:  prometheus.GaugeValue,
+	}
+	particulateMatterCount10 = ValueDesc{
+		Index: 5,
+		Name:  "particles_1_0um",
+		Help:  "Number of particles greater than 1.0µm in 100 ml of air",
+		Type:  prometheus.GaugeValue,
+	}
+	particulateMatterCount25 = ValueDesc{
+		Index: 6,
+		Name:  "particles_2_5um",
+		Help:  "Number of particles greater than 2.5µm in 100 ml of air",
+		Type:  prometheus.GaugeValue,
+	}
+	particulateMatterCount50 = ValueDesc{
+		Index: 7,
+		Name:  "particles_5_0um",
+		Help:  "Number of particles greater than 5.0µm in 100 ml of air",
+		Type:  prometheus.GaugeValue,
+	}
+	particulateMatterCount100 = ValueDesc{
+		Index: 8,
+		Name:  "particles_10_0um",
+		Help:  "Number of particles greater than 10.0µm in 100 ml of air",
+		Type:  prometheus.GaugeValue,
+	}
+	particulateMatterEnabled = ValueDesc{
+		Index:       9,
+		Name:        "particulate_matter_sensor_enabled",
+		Help:        "Whether the fan and laser of the particulate matter sensor are enabled (1) or not (0)",
+		Type:        prometheus.GaugeValue,
+		HAType:      "binary_sensor",
+		DeviceClass: "running",
+	}
+)
+
+type particulateMatterBricklet struct{}
+
+func (particulateMatterBricklet) DeviceIdentifier() uint16 {
+	return particulate_matter_bricklet.DeviceIdentifier
+}
+func (particulateMatterBricklet) Name() string { return "particulate_matter_bricklet" }
+
+// measurements are the values which are only sent while the sensor is enabled
+func (particulateMatterBricklet) measurements() []ValueDesc {
+	return []ValueDesc{
+		particulateMatterPM1,
+		particulateMatterPM25,
+		particulateMatterPM10,
+		particulateMatterCount03,
+		particulateMatterCount05,
+		particulateMatterCount10,
+		particulateMatterCount25,
+		particulateMatterCount50,
+		particulateMatterCount100,
+	}
+}
+
+func (pm particulateMatterBricklet) Values() []ValueDesc {
+	return append(pm.measurements(), particulateMatterEnabled)
+}
+
+func (pm particulateMatterBricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := particulate_matter_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Particulate Matter Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	// while the sensor is disabled the bricklet sends the last known values, these are
+	// not exported
+	var enabled atomic.Bool
+
+	concID := d.RegisterPMConcentrationCallback(func(pm10 uint16, pm25 uint16, pm100 uint16) {
+		if !enabled.Load() {
+			return
+		}
+		b.Send(dev, 0, particulateMatterPM1, float64(pm10))
+		b.Send(dev, 0, particulateMatterPM25, float64(pm25))
+		b.Send(dev, 0, particulateMatterPM10, float64(pm100))
+	})
+	d.SetPMConcentrationCallbackConfiguration(b.CallbackPeriod, false)
+
+	countID := d.RegisterPMCountCallback(func(greater03um uint16, greater05um uint16, greater10um uint16,
+		greater25um uint16, greater50um uint16, greater100um uint16) {
+		if !enabled.Load() {
+			return
+		}
+		b.Send(dev, 0, particulateMatterCount03, float64(greater03um))
+		b.Send(dev, 0, particulateMatterCount05, float64(greater05um))
+		b.Send(dev, 0, particulateMatterCount10, float64(greater10um))
+		b.Send(dev, 0, particulateMatterCount25, float64(greater25um))
+		b.Send(dev, 0, particulateMatterCount50, float64(greater50um))
+		b.Send(dev, 0, particulateMatterCount100, float64(greater100um))
+	})
+	d.SetPMCountCallbackConfiguration(b.CallbackPeriod, false)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterPMConcentrationCallback,
+			ID:         concID,
+		},
+		{
+			Deregister: d.DeregisterPMCountCallback,
+			ID:         countID,
+		},
+		b.poll(make(chan struct{}), func() { pm.readEnabled(b, &d, dev, &enabled) }),
+	}, nil
+}
+
+// readEnabled reads whether the sensor is enabled, the measurements are removed when it is
+// disabled
+func (pm particulateMatterBricklet) readEnabled(b *BrickdCollector, d *particulate_matter_bricklet.ParticulateMatterBricklet,
+	dev *Device, enabled *atomic.Bool) {
+	on, err := d.GetEnable()
+	if err != nil {
+		log.Infof("failed to get state of Particulate Matter Bricklet (uid=%s): %s", dev.UID, err)
+		return
+	}
+	enabled.Store(on)
+	if !on {
+		b.RemoveValues(dev, 0, pm.measurements())
+	}
+	b.Send(dev, 0, particulateMatterEnabled, bool2Float(on))
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/ptc_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(ptcV2Bricklet{})
+}
+
+var (
+	ptcV2Temperature = ValueDesc{
+		Index:       0,
+		Name:        "temperature",
+		Help:        "Temperature in °C",
+		Type:        prometheus.GaugeValue,
+		Unit:        "°C",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "temperature",
+	}
+	ptcV2SensorConnected = ValueDesc{
+		Index:       1,
+		Name:        "sensor_connected",
+		Help:        "Whether the probe is connected (1) or not (0)",
+		Type:        prometheus.GaugeValue,
+		HAType:      "binary_sensor",
+		DeviceClass: "connectivity",
+	}
+	ptcV2WireMode = ValueDesc{
+		Index: 2,
+		Name:  "wire_mode",
+		Help:  "Wire mode of the probe, 2, 3 or 4 wire",
+		Type:  prometheus.GaugeValue,
+	}
+)
+
+type ptcV2Bricklet struct{}
+
+func (ptcV2Bricklet) DeviceIdentifier() uint16 { return ptc_v2_bricklet.DeviceIdentifier }
+func (ptcV2Bricklet) Name() string             { return "ptc_bricklet_v2" }
+
+func (ptcV2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{ptcV2Temperature, ptcV2SensorConnected, ptcV2WireMode}
+}
+
+func (p ptcV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := ptc_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect PTC Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	tempID := d.RegisterTemperatureCallback(func(temperature int32) {
+		b.Send(dev, 0, ptcV2Temperature, float64(temperature))
+	})
+	d.SetTemperatureCallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	// the callback is only triggered when the probe is connected or disconnected, the state
+	// and the wire mode are polled so they don't expire
+	connID := d.RegisterSensorConnectedCallback(func(connected bool) {
+		b.Send(dev, 0, ptcV2SensorConnected, bool2Float(connected))
+	})
+	d.SetSensorConnectedCallbackConfiguration(true)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterTemperatureCallback,
+			ID:         tempID,
+		},
+		{
+			Deregister: d.DeregisterSensorConnectedCallback,
+			ID:         connID,
+		},
+		b.poll(make(chan struct{}), func() { p.readProbe(b, &d, dev) }),
+	}, nil
+}
+
+// readProbe reads whether the probe is connected and its wire mode
+func (ptcV2Bricklet) readProbe(b *BrickdCollector, d *ptc_v2_bricklet.PTCV2Bricklet, dev *Device) {
+	if connected, err := d.IsSensorConnected(); err != nil {
+		log.Infof("failed to get sensor connection of PTC Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	} else {
+		b.Send(dev, 0, ptcV2SensorConnected, bool2Float(connected))
+	}
+
+	if mode, err := d.GetWireMode(); err != nil {
+		log.Infof("failed to get wire mode of PTC Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	} else {
+		b.Send(dev, 0, ptcV2WireMode, float64(mode))
+	}
+}
@@ -0,0 +1,40 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/solid_state_relay_v2_bricklet"
+)
+
+func init() {
+	RegisterDriver(solidStateRelayV2Bricklet{})
+}
+
+type solidStateRelayV2Bricklet struct{}
+
+func (solidStateRelayV2Bricklet) DeviceIdentifier() uint16 {
+	return solid_state_relay_v2_bricklet.DeviceIdentifier
+}
+func (solidStateRelayV2Bricklet) Name() string { return "solid_state_relay_bricklet_v2" }
+func (solidStateRelayV2Bricklet) MultiSensor() {}
+
+func (solidStateRelayV2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{outputState}
+}
+
+func (r solidStateRelayV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := solid_state_relay_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Solid State Relay Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	// the bricklet has a single relay, exported as channel 0
+	return b.RegisterOutputs(r, dev, 1, func() ([]bool, error) {
+		state, err := d.GetState()
+		return []bool{state}, err
+	}, func(_ uint8, on bool) error {
+		return d.SetState(on)
+	}), nil
+}
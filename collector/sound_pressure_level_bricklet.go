@@ -0,0 +1,201 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/Tinkerforge/go-api-bindings/sound_pressure_level_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(soundPressureLevelBricklet{})
+}
+
+var (
+	soundPressureLevelDecibel = ValueDesc{
+		Index:       0,
+		Name:        "sound_pressure_level",
+		Help:        "Sound pressure level in dB with the frequency weighting given in the weighting label",
+		Type:        prometheus.GaugeValue,
+		Unit:        "dB",
+		Scale:       0.1,
+		HAType:      "sensor",
+		DeviceClass: "sound_pressure",
+		Labels:      []string{"weighting"},
+	}
+	// soundPressureLevelSpectrum is sent for each frequency bin of the spectrum, the Index
+	// is the one of the first bin
+	soundPressureLevelSpectrum = ValueDesc{
+		Index:  1,
+		Name:   "sound_spectrum",
+		Help:   "Sound pressure level in dB of the frequency bin starting at the frequency label in Hz",
+		Type:   prometheus.GaugeValue,
+		Unit:   "dB",
+		Labels: []string{"weighting", "frequency"},
+	}
+)
+
+// soundPressureLevelFFTSizes maps the `fft_size` config values to the FFT size of the bricklet
+var soundPressureLevelFFTSizes = map[int]uint8{
+	128:  sound_pressure_level_bricklet.FFTSize128,
+	256:  sound_pressure_level_bricklet.FFTSize256,
+	512:  sound_pressure_level_bricklet.FFTSize512,
+	1024: sound_pressure_level_bricklet.FFTSize1024,
+}
+
+// soundPressureLevelWeightings maps the `weighting` config values to the weighting of the
+// bricklet
+var soundPressureLevelWeightings = map[string]uint8{
+	"A": sound_pressure_level_bricklet.WeightingA,
+	"B": sound_pressure_level_bricklet.WeightingB,
+	"C": sound_pressure_level_bricklet.WeightingC,
+	"D": sound_pressure_level_bricklet.WeightingD,
+	"Z": sound_pressure_level_bricklet.WeightingZ,
+}
+
+// SoundPressureLevelSettings configures all Sound Pressure Level Bricklets
+type SoundPressureLevelSettings struct {
+	FFTSize   int    `yaml:"fft_size"`  // 128, 256, 512 or 1024, 0 is the default of 1024
+	Weighting string `yaml:"weighting"` // "A", "B", "C", "D" or "Z", empty is the default of "A"
+	Spectrum  bool   `yaml:"spectrum"`  // export the spectrum
+}
+
+// Validate returns an error if the FFT size or weighting are invalid
+func (s SoundPressureLevelSettings) Validate() error {
+	if _, ok := soundPressureLevelFFTSizes[s.FFTSize]; !ok && s.FFTSize != 0 {
+		return fmt.Errorf("invalid FFT size %d, must be one of 128, 256, 512 or 1024", s.FFTSize)
+	}
+	if _, ok := soundPressureLevelWeightings[s.Weighting]; !ok && s.Weighting != "" {
+		return fmt.Errorf("invalid weighting %q, must be one of \"A\", \"B\", \"C\", \"D\" or \"Z\"", s.Weighting)
+	}
+	return nil
+}
+
+// withDefaults returns the settings with the defaults of the bricklet for unset values
+func (s SoundPressureLevelSettings) withDefaults() SoundPressureLevelSettings {
+	if s.FFTSize == 0 {
+		s.FFTSize = 1024
+	}
+	if s.Weighting == "" {
+		s.Weighting = "A"
+	}
+	return s
+}
+
+type soundPressureLevelBricklet struct{}
+
+func (soundPressureLevelBricklet) DeviceIdentifier() uint16 {
+	return sound_pressure_level_bricklet.DeviceIdentifier
+}
+func (soundPressureLevelBricklet) Name() string { return "sound_pressure_level_bricklet" }
+
+func (soundPressureLevelBricklet) Values() []ValueDesc {
+	return []ValueDesc{soundPressureLevelDecibel, soundPressureLevelSpectrum}
+}
+
+func (soundPressureLevelBricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := sound_pressure_level_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Sound Pressure Level Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	b.RLock()
+	s := b.SoundPressureLevel.withDefaults()
+	b.RUnlock()
+
+	if err := d.SetConfiguration(soundPressureLevelFFTSizes[s.FFTSize], soundPressureLevelWeightings[s.Weighting]); err != nil {
+		log.Errorf("failed to configure Sound Pressure Level Bricklet (uid=%s): %s", dev.UID, err)
+	}
+	labels := map[string]string{"weighting": s.Weighting}
+
+	decibelID := d.RegisterDecibelCallback(func(decibel uint16) {
+		b.SendLabels(dev, 0, soundPressureLevelDecibel, float64(decibel), labels)
+	})
+	d.SetDecibelCallbackConfiguration(b.CallbackPeriod, false, 'x', 0, 0)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	regs := []Register{
+		{
+			Deregister: d.DeregisterDecibelCallback,
+			ID:         decibelID,
+		},
+	}
+	if !s.Spectrum {
+		return regs, nil
+	}
+
+	// the binding calls each callback in its own goroutine, so the chunks of the spectrum
+	// are collected here instead of using the racy RegisterSpectrumCallback
+	var spectrum spectrumChunks
+	spectrumID := d.RegisterSpectrumLowLevelCallback(func(length uint16, offset uint16, chunk [30]uint16) {
+		bins := spectrum.add(length, offset, chunk)
+		if len(bins) == 0 {
+			return
+		}
+		width := 20480 / len(bins)
+		// bin 0 is the DC offset, not a sound
+		for i := 1; i < len(bins); i++ {
+			desc := soundPressureLevelSpectrum
+			desc.Index += i - 1
+			b.SendLabels(dev, 0, desc, spectrumDecibel(bins[i]), map[string]string{
+				"weighting": s.Weighting,
+				"frequency": strconv.Itoa(i * width),
+			})
+		}
+	})
+	d.SetSpectrumCallbackConfiguration(b.CallbackPeriod)
+
+	return append(regs, Register{
+		Deregister: d.DeregisterSpectrumLowLevelCallback,
+		ID:         spectrumID,
+	}), nil
+}
+
+// spectrumChunks assembles the spectrum from the chunks of the low level callback, which may
+// arrive in any order
+type spectrumChunks struct {
+	sync.Mutex
+	bins     []uint16
+	received []bool
+	missing  int
+}
+
+// add stores a chunk and returns the spectrum when it is complete
+func (sc *spectrumChunks) add(length, offset uint16, chunk [30]uint16) []uint16 {
+	sc.Lock()
+	defer sc.Unlock()
+	if offset >= length {
+		return nil
+	}
+	if int(length) != len(sc.bins) || sc.missing == 0 || sc.received[offset] {
+		sc.bins = make([]uint16, length)
+		sc.received = make([]bool, length)
+		sc.missing = int(length)
+	}
+	for i, v := range chunk {
+		pos := int(offset) + i
+		if pos >= len(sc.bins) {
+			break
+		}
+		if !sc.received[pos] {
+			sc.received[pos] = true
+			sc.missing--
+		}
+		sc.bins[pos] = v
+	}
+	if sc.missing != 0 {
+		return nil
+	}
+	return sc.bins
+}
+
+// spectrumDecibel converts a value of the spectrum to dB, see the documentation of
+// GetSpectrum of the Sound Pressure Level Bricklet
+func spectrumDecibel(v uint16) float64 {
+	return 20 * math.Log10(math.Max(1, float64(v)/math.Sqrt2))
+}
@@ -0,0 +1,136 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// the values of the distance bricklets, the tank values are only sent for the bricklets with
+// a TankSettings
+var (
+	distanceDistance = ValueDesc{
+		Index:       0,
+		Name:        "distance",
+		Help:        "Distance measured by the sensor in m",
+		Type:        prometheus.GaugeValue,
+		Unit:        "m",
+		HAType:      "sensor",
+		DeviceClass: "distance",
+	}
+	distanceTankLevel = ValueDesc{
+		Index:       1,
+		Name:        "tank_level",
+		Help:        "Fill level of the tank in m, calculated from the distance and the tank height",
+		Type:        prometheus.GaugeValue,
+		Unit:        "m",
+		HAType:      "sensor",
+		DeviceClass: "distance",
+	}
+	distanceTankVolume = ValueDesc{
+		Index:       2,
+		Name:        "tank_volume",
+		Help:        "Volume of the tank content in l, calculated from the fill level",
+		Type:        prometheus.GaugeValue,
+		Unit:        "L",
+		HAType:      "sensor",
+		DeviceClass: "volume",
+	}
+)
+
+// distanceValues are the values of all distance bricklets
+func distanceValues() []ValueDesc {
+	return []ValueDesc{distanceDistance, distanceTankLevel, distanceTankVolume}
+}
+
+// TankSettings describe the tank measured by a distance bricklet mounted at its top
+type TankSettings struct {
+	Height   float64      `yaml:"height"`   // distance from the sensor to the bottom of the tank in m
+	Capacity float64      `yaml:"capacity"` // volume in l when filled up to the sensor, for tanks with vertical walls
+	Volumes  []TankVolume `yaml:"volumes"`  // volume by fill level for other shapes, used instead of Capacity
+}
+
+// TankVolume is the volume of the tank content at a fill level, the volume between two
+// levels is interpolated linearly
+type TankVolume struct {
+	Level  float64 `yaml:"level"`  // fill level in m
+	Volume float64 `yaml:"volume"` // volume in l
+}
+
+// Validate returns an error if the height is missing or the volume table is not sorted
+func (t TankSettings) Validate() error {
+	if t.Height <= 0 {
+		return fmt.Errorf("height must be greater than 0")
+	}
+	if t.Capacity < 0 {
+		return fmt.Errorf("capacity must not be negative")
+	}
+	for i := 1; i < len(t.Volumes); i++ {
+		if t.Volumes[i].Level <= t.Volumes[i-1].Level || t.Volumes[i].Volume < t.Volumes[i-1].Volume {
+			return fmt.Errorf("volumes must be sorted by increasing level and volume")
+		}
+	}
+	return nil
+}
+
+// hasVolume returns whether the volume can be calculated
+func (t TankSettings) hasVolume() bool {
+	return t.Capacity > 0 || len(t.Volumes) > 0
+}
+
+// level returns the fill level for the distance from the sensor to the surface, both in m
+func (t TankSettings) level(distance float64) float64 {
+	return min(max(t.Height-distance, 0), t.Height)
+}
+
+// volume returns the volume in l at a fill level in m
+func (t TankSettings) volume(level float64) float64 {
+	if len(t.Volumes) == 0 {
+		return t.Capacity * level / t.Height
+	}
+	if level <= t.Volumes[0].Level {
+		return t.Volumes[0].Volume
+	}
+	for i := 1; i < len(t.Volumes); i++ {
+		lo, hi := t.Volumes[i-1], t.Volumes[i]
+		if level <= hi.Level {
+			return lo.Volume + (hi.Volume-lo.Volume)*(level-lo.Level)/(hi.Level-lo.Level)
+		}
+	}
+	return t.Volumes[len(t.Volumes)-1].Volume
+}
+
+// tankValues returns the values of a distance bricklet to publish the HomeAssistant config
+// for, the tank values only with a TankSettings for the device
+func (b *BrickdCollector) tankValues(dev *Device) []ValueDesc {
+	b.RLock()
+	tank, ok := b.Tanks[dev.UID]
+	b.RUnlock()
+
+	values := []ValueDesc{distanceDistance}
+	if ok {
+		values = append(values, distanceTankLevel)
+		if tank.hasVolume() {
+			values = append(values, distanceTankVolume)
+		}
+	}
+	return values
+}
+
+// sendDistance sends the distance in m measured by a distance bricklet and the fill level
+// and volume of its tank
+func (b *BrickdCollector) sendDistance(dev *Device, distance float64) {
+	b.Send(dev, 0, distanceDistance, distance)
+
+	b.RLock()
+	tank, ok := b.Tanks[dev.UID]
+	b.RUnlock()
+	if !ok {
+		return
+	}
+	level := tank.level(distance)
+	b.Send(dev, 0, distanceTankLevel, level)
+	if tank.hasVolume() {
+		b.Send(dev, 0, distanceTankVolume, tank.volume(level))
+	}
+}
@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/temperature_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(temperatureV2Bricklet{})
+}
+
+var temperatureV2Temperature = ValueDesc{
+	Index:       0,
+	Name:        "temperature",
+	Help:        "Temperature in °C",
+	Type:        prometheus.GaugeValue,
+	Unit:        "°C",
+	Scale:       0.01,
+	HAType:      "sensor",
+	DeviceClass: "temperature",
+}
+
+type temperatureV2Bricklet struct{}
+
+func (temperatureV2Bricklet) DeviceIdentifier() uint16 {
+	return temperature_v2_bricklet.DeviceIdentifier
+}
+func (temperatureV2Bricklet) Name() string { return "temperature_bricklet_v2" }
+
+func (temperatureV2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{temperatureV2Temperature}
+}
+
+func (temperatureV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := temperature_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Temperature Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	tempID := d.RegisterTemperatureCallback(func(temperature int16) {
+		b.Send(dev, 0, temperatureV2Temperature, float64(temperature))
+	})
+	d.SetTemperatureCallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterTemperatureCallback,
+			ID:         tempID,
+		},
+	}, nil
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/thermocouple_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(thermocoupleV2Bricklet{})
+}
+
+var (
+	thermocoupleV2Temperature = ValueDesc{
+		Index:       0,
+		Name:        "temperature",
+		Help:        "Temperature in °C",
+		Type:        prometheus.GaugeValue,
+		Unit:        "°C",
+		Scale:       0.01,
+		HAType:      "sensor",
+		DeviceClass: "temperature",
+	}
+	thermocoupleV2OverUnderVoltage = ValueDesc{
+		Index:       1,
+		Name:        "over_under_voltage",
+		Help:        "Whether the thermocouple reports an over or under voltage error (1) or not (0)",
+		Type:        prometheus.GaugeValue,
+		HAType:      "binary_sensor",
+		DeviceClass: "problem",
+	}
+	thermocoupleV2OpenCircuit = ValueDesc{
+		Index:       2,
+		Name:        "open_circuit",
+		Help:        "Whether the thermocouple reports an open circuit, e.g. no probe connected (1) or not (0)",
+		Type:        prometheus.GaugeValue,
+		HAType:      "binary_sensor",
+		DeviceClass: "problem",
+	}
+)
+
+type thermocoupleV2Bricklet struct{}
+
+func (thermocoupleV2Bricklet) DeviceIdentifier() uint16 {
+	return thermocouple_v2_bricklet.DeviceIdentifier
+}
+func (thermocoupleV2Bricklet) Name() string { return "thermocouple_bricklet_v2" }
+
+func (thermocoupleV2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{thermocoupleV2Temperature, thermocoupleV2OverUnderVoltage, thermocoupleV2OpenCircuit}
+}
+
+func (tc thermocoupleV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := thermocouple_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Thermocouple Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	tempID := d.RegisterTemperatureCallback(func(temperature int32) {
+		b.Send(dev, 0, thermocoupleV2Temperature, float64(temperature))
+	})
+	d.SetTemperatureCallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	// the callback is only triggered when the error state changes, the state is polled so it
+	// doesn't expire
+	errID := d.RegisterErrorStateCallback(func(overUnder bool, openCircuit bool) {
+		b.Send(dev, 0, thermocoupleV2OverUnderVoltage, bool2Float(overUnder))
+		b.Send(dev, 0, thermocoupleV2OpenCircuit, bool2Float(openCircuit))
+	})
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterTemperatureCallback,
+			ID:         tempID,
+		},
+		{
+			Deregister: d.DeregisterErrorStateCallback,
+			ID:         errID,
+		},
+		b.poll(make(chan struct{}), func() { tc.readErrorState(b, &d, dev) }),
+	}, nil
+}
+
+// readErrorState reads the error state
+func (thermocoupleV2Bricklet) readErrorState(b *BrickdCollector, d *thermocouple_v2_bricklet.ThermocoupleV2Bricklet, dev *Device) {
+	overUnder, openCircuit, err := d.GetErrorState()
+	if err != nil {
+		log.Infof("failed to get error state of Thermocouple Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+		return
+	}
+	b.Send(dev, 0, thermocoupleV2OverUnderVoltage, bool2Float(overUnder))
+	b.Send(dev, 0, thermocoupleV2OpenCircuit, bool2Float(openCircuit))
+}
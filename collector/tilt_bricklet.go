@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/tilt_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(tiltBricklet{})
+}
+
+var (
+	tiltOpen = ValueDesc{
+		Index:  0,
+		Name:   "tilt",
+		Help:   "Tilt switch open (1), i.e. tilted, or closed (0)",
+		Type:   prometheus.GaugeValue,
+		HAType: "binary_sensor",
+	}
+	tiltVibration = ValueDesc{
+		Index:       1,
+		Name:        "tilt_vibration",
+		Help:        "Tilt switch closed and vibrating (1) or not (0)",
+		Type:        prometheus.GaugeValue,
+		HAType:      "binary_sensor",
+		DeviceClass: "vibration",
+	}
+	tiltEvents = eventCount(2, "tilt_events", "Number of changes of the tilt switch state")
+)
+
+type tiltBricklet struct{}
+
+func (tiltBricklet) DeviceIdentifier() uint16 { return tilt_bricklet.DeviceIdentifier }
+func (tiltBricklet) Name() string             { return "tilt_bricklet" }
+
+func (tiltBricklet) Values() []ValueDesc {
+	return []ValueDesc{tiltOpen, tiltVibration, tiltEvents}
+}
+
+func (tiltBricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := tilt_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Tilt Bricklet (uid=%s): %s", dev.UID, err)
+	}
+
+	stateID := d.RegisterTiltStateCallback(func(state tilt_bricklet.TiltState) {
+		b.Send(dev, 0, tiltVibration, bool2Float(state == tilt_bricklet.TiltStateClosedVibrating))
+		b.SendEvent(dev, 0, tiltOpen, bool2Float(state == tilt_bricklet.TiltStateOpen), tiltEvents)
+	})
+	if err := d.EnableTiltStateCallback(); err != nil {
+		log.Errorf("failed to enable tilt state callback (uid=%s): %s", dev.UID, err)
+	}
+
+	sendState := func() {
+		if state, err := d.GetTiltState(); err != nil {
+			log.Infof("failed to get tilt state (uid=%s): %s", dev.UID, err)
+		} else {
+			b.Send(dev, 0, tiltVibration, bool2Float(state == tilt_bricklet.TiltStateClosedVibrating))
+			b.SendState(dev, 0, tiltOpen, bool2Float(state == tilt_bricklet.TiltStateOpen))
+		}
+	}
+
+	return []Register{
+		{
+			Deregister: d.DeregisterTiltStateCallback,
+			ID:         stateID,
+		},
+		b.repeatEvents(dev, 0, tiltEvents, sendState),
+	}, nil
+}
@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/uv_light_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	RegisterDriver(uvLightV2Bricklet{})
+}
+
+var uvLightV2UV = ValueDesc{
+	Index:  0,
+	Name:   "uv",
+	Help:   "UV in mW/m²",
+	Type:   prometheus.GaugeValue,
+	Unit:   "mW/m²",
+	Scale:  0.1,
+	HAType: "sensor",
+}
+
+type uvLightV2Bricklet struct{}
+
+func (uvLightV2Bricklet) DeviceIdentifier() uint16 { return uv_light_v2_bricklet.DeviceIdentifier }
+func (uvLightV2Bricklet) Name() string             { return "uv_light_v2_bricklet" }
+func (uvLightV2Bricklet) Values() []ValueDesc      { return []ValueDesc{uvLightV2UV} }
+
+func (uvLightV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := uv_light_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Ultra Violet Light V2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	uvID := d.RegisterUVACallback(func(uva int32) {
+		b.Send(dev, 0, uvLightV2UV, float64(uva))
+	})
+	d.SetUVACallbackConfiguration(b.CallbackPeriod, true, 'x', 0, 0)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterUVACallback,
+			ID:         uvID,
+		},
+	}, nil
+}
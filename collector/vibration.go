@@ -0,0 +1,143 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// the acceleration of the IMUs and accelerometers is sampled at a high rate for the devices
+// with VibrationSettings. Instead of the samples, the RMS, peak and crest factor of each axis
+// are exported every callback period.
+
+// axes are the values of the "axis" label, the Index of an axis value is the Index of the
+// value plus the position of the axis
+var axes = []string{"x", "y", "z"}
+
+// vibrationValues returns the vibration summary values of a device, starting at index
+func vibrationValues(index int) []ValueDesc {
+	return []ValueDesc{
+		{
+			Index:  index,
+			Name:   "vibration_rms",
+			Help:   "RMS of the acceleration minus its mean over the callback period in m/s²",
+			Type:   prometheus.GaugeValue,
+			Unit:   "m/s²",
+			Labels: []string{"axis"},
+		},
+		{
+			Index:  index + len(axes),
+			Name:   "vibration_peak",
+			Help:   "Maximum deviation of the acceleration from its mean over the callback period in m/s²",
+			Type:   prometheus.GaugeValue,
+			Unit:   "m/s²",
+			Labels: []string{"axis"},
+		},
+		{
+			Index:  index + 2*len(axes),
+			Name:   "vibration_crest_factor",
+			Help:   "Peak divided by RMS of the acceleration over the callback period",
+			Type:   prometheus.GaugeValue,
+			Labels: []string{"axis"},
+		},
+	}
+}
+
+// VibrationSettings enable the vibration summary of an IMU or accelerometer
+type VibrationSettings struct {
+	SampleRate int `yaml:"sample_rate"` // samples per second, 0 for the default of the device
+	FullScale  int `yaml:"full_scale"`  // range of the Accelerometer Bricklet 2.0 in gₙ, 2, 4 or 8, 0 keeps the configuration of the bricklet
+}
+
+// Validate returns an error if the sample rate or full scale are invalid
+func (s VibrationSettings) Validate() error {
+	if s.SampleRate < 0 {
+		return fmt.Errorf("sample_rate must not be negative")
+	}
+	if s.SampleRate > accelerometerV2MaxSampleRate {
+		return fmt.Errorf("sample_rate must not be higher than %d", accelerometerV2MaxSampleRate)
+	}
+	if _, ok := accelerometerV2FullScales[s.FullScale]; !ok && s.FullScale != 0 {
+		return fmt.Errorf("invalid full_scale %d, must be one of 2, 4 or 8", s.FullScale)
+	}
+	return nil
+}
+
+// vibration aggregates the acceleration samples of the axes over a callback period
+type vibration struct {
+	sync.Mutex
+	n              int
+	sum, sumSq     [3]float64
+	minAcc, maxAcc [3]float64
+}
+
+// add adds samples of the acceleration in m/s², the samples of a callback are added at once
+// so they are in the same summary
+func (v *vibration) add(samples ...[3]float64) {
+	v.Lock()
+	defer v.Unlock()
+	for _, acc := range samples {
+		for i, a := range acc {
+			if v.n == 0 || a < v.minAcc[i] {
+				v.minAcc[i] = a
+			}
+			if v.n == 0 || a > v.maxAcc[i] {
+				v.maxAcc[i] = a
+			}
+			v.sum[i] += a
+			v.sumSq[i] += a * a
+		}
+		v.n++
+	}
+}
+
+// vibrationSummary is the summary of an axis over a callback period
+type vibrationSummary struct {
+	mean, rms, peak, crest float64
+}
+
+// summary returns the summary of each axis and starts a new period, ok is false when there
+// were no samples
+func (v *vibration) summary() (s [3]vibrationSummary, ok bool) {
+	v.Lock()
+	defer v.Unlock()
+	if v.n == 0 {
+		return s, false
+	}
+	for i := range s {
+		mean := v.sum[i] / float64(v.n)
+		s[i].mean = mean
+		s[i].rms = math.Sqrt(math.Max(0, v.sumSq[i]/float64(v.n)-mean*mean))
+		s[i].peak = math.Max(v.maxAcc[i]-mean, mean-v.minAcc[i])
+		if s[i].rms > 0 {
+			s[i].crest = s[i].peak / s[i].rms
+		}
+	}
+	// the minimum and maximum are replaced by the first sample
+	v.n, v.sum, v.sumSq = 0, [3]float64{}, [3]float64{}
+	return s, true
+}
+
+// sendVibration sends the vibration summary of each axis every callback period, sent is
+// called with the summary after sending it. The returned Register stops it.
+func (b *BrickdCollector) sendVibration(dev *Device, v *vibration, values []ValueDesc, sent func([3]vibrationSummary)) Register {
+	return b.poll(make(chan struct{}), func() {
+		summary, ok := v.summary()
+		if !ok {
+			return
+		}
+		for i, axis := range axes {
+			labels := map[string]string{"axis": axis}
+			for j, raw := range []float64{summary[i].rms, summary[i].peak, summary[i].crest} {
+				desc := values[j]
+				desc.Index += i
+				b.SendLabels(dev, 0, desc, raw, labels)
+			}
+		}
+		if sent != nil {
+			sent(summary)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/Tinkerforge/go-api-bindings/voltage_current_v2_bricklet"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDriver(voltageCurrentV2Bricklet{})
+}
+
+var (
+	voltageCurrentV2Voltage = ValueDesc{
+		Index:       0,
+		Name:        "voltage",
+		Help:        "Voltage in V",
+		Type:        prometheus.GaugeValue,
+		Unit:        "V",
+		Scale:       0.001,
+		HAType:      "sensor",
+		DeviceClass: "voltage",
+	}
+	voltageCurrentV2Current = ValueDesc{
+		Index:       1,
+		Name:        "current",
+		Help:        "Current in A",
+		Type:        prometheus.GaugeValue,
+		Unit:        "A",
+		Scale:       0.001,
+		HAType:      "sensor",
+		DeviceClass: "current",
+	}
+	voltageCurrentV2Power = ValueDesc{
+		Index:       2,
+		Name:        "power",
+		Help:        "Power in W",
+		Type:        prometheus.GaugeValue,
+		Unit:        "W",
+		Scale:       0.001,
+		HAType:      "sensor",
+		DeviceClass: "power",
+	}
+)
+
+// voltageCurrentAveraging maps the `averaging` config values to the averaging of the bricklet
+var voltageCurrentAveraging = map[int]uint8{
+	1:    voltage_current_v2_bricklet.Averaging1,
+	4:    voltage_current_v2_bricklet.Averaging4,
+	16:   voltage_current_v2_bricklet.Averaging16,
+	64:   voltage_current_v2_bricklet.Averaging64,
+	128:  voltage_current_v2_bricklet.Averaging128,
+	256:  voltage_current_v2_bricklet.Averaging256,
+	512:  voltage_current_v2_bricklet.Averaging512,
+	1024: voltage_current_v2_bricklet.Averaging1024,
+}
+
+// VoltageCurrentSettings configure a Voltage/Current Bricklet 2.0
+type VoltageCurrentSettings struct {
+	Averaging   int                        `yaml:"averaging"`   // number of samples averaged, 1, 4, 16, ... 1024, 0 keeps the configuration of the bricklet
+	Calibration *VoltageCurrentCalibration `yaml:"calibration"` // written to the bricklet when different, nil keeps the calibration of the bricklet
+}
+
+// VoltageCurrentCalibration corrects the measured voltage and current by multiplier / divisor,
+// e.g. a current multiplier of 1000 and divisor of 1023 when measuring 1023 mA for 1000 mA
+type VoltageCurrentCalibration struct {
+	VoltageMultiplier uint16 `yaml:"voltage_multiplier"`
+	VoltageDivisor    uint16 `yaml:"voltage_divisor"`
+	CurrentMultiplier uint16 `yaml:"current_multiplier"`
+	CurrentDivisor    uint16 `yaml:"current_divisor"`
+}
+
+// Validate returns an error if the averaging is invalid or a calibration value is missing
+func (s VoltageCurrentSettings) Validate() error {
+	if _, ok := voltageCurrentAveraging[s.Averaging]; !ok && s.Averaging != 0 {
+		return fmt.Errorf("invalid averaging %d, must be one of 1, 4, 16, 64, 128, 256, 512 or 1024", s.Averaging)
+	}
+	if c := s.Calibration; c != nil {
+		if c.VoltageMultiplier == 0 || c.VoltageDivisor == 0 || c.CurrentMultiplier == 0 || c.CurrentDivisor == 0 {
+			return fmt.Errorf("calibration multipliers and divisors must be greater than 0")
+		}
+	}
+	return nil
+}
+
+type voltageCurrentV2Bricklet struct{}
+
+func (voltageCurrentV2Bricklet) DeviceIdentifier() uint16 {
+	return voltage_current_v2_bricklet.DeviceIdentifier
+}
+func (voltageCurrentV2Bricklet) Name() string { return "voltage_current_bricklet_v2" }
+
+func (voltageCurrentV2Bricklet) Values() []ValueDesc {
+	return []ValueDesc{voltageCurrentV2Voltage, voltageCurrentV2Current, voltageCurrentV2Power}
+}
+
+func (vc voltageCurrentV2Bricklet) Register(b *BrickdCollector, dev *Device) ([]Register, error) {
+	d, err := voltage_current_v2_bricklet.New(dev.UID, &b.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect Voltage/Current Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+	}
+
+	b.RLock()
+	s := b.VoltageCurrent[dev.UID]
+	b.RUnlock()
+	vc.configure(&d, dev, s)
+
+	voltageID := d.RegisterVoltageCallback(func(voltage int32) {
+		b.Send(dev, 0, voltageCurrentV2Voltage, float64(voltage))
+	})
+	d.SetVoltageCallbackConfiguration(b.CallbackPeriod, false, 'x', 0, 0)
+
+	currentID := d.RegisterCurrentCallback(func(current int32) {
+		b.Send(dev, 0, voltageCurrentV2Current, float64(current))
+	})
+	d.SetCurrentCallbackConfiguration(b.CallbackPeriod, false, 'x', 0, 0)
+
+	powerID := d.RegisterPowerCallback(func(power int32) {
+		b.Send(dev, 0, voltageCurrentV2Power, float64(power))
+	})
+	d.SetPowerCallbackConfiguration(b.CallbackPeriod, false, 'x', 0, 0)
+
+	b.setStatusLED(dev, d.SetStatusLEDConfig)
+
+	return []Register{
+		{
+			Deregister: d.DeregisterVoltageCallback,
+			ID:         voltageID,
+		},
+		{
+			Deregister: d.DeregisterCurrentCallback,
+			ID:         currentID,
+		},
+		{
+			Deregister: d.DeregisterPowerCallback,
+			ID:         powerID,
+		},
+	}, nil
+}
+
+// configure sets the averaging and calibration of the bricklet. The calibration is stored in
+// the EEPROM of the bricklet, so it is only written when it differs.
+func (voltageCurrentV2Bricklet) configure(d *voltage_current_v2_bricklet.VoltageCurrentV2Bricklet, dev *Device, s VoltageCurrentSettings) {
+	if s.Averaging != 0 {
+		_, voltageTime, currentTime, err := d.GetConfiguration()
+		if err == nil {
+			err = d.SetConfiguration(voltageCurrentAveraging[s.Averaging], voltageTime, currentTime)
+		}
+		if err != nil {
+			log.Errorf("failed to set averaging of Voltage/Current Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+		}
+	}
+
+	if c := s.Calibration; c != nil {
+		vm, vd, cm, cd, err := d.GetCalibration()
+		if err != nil {
+			log.Errorf("failed to get calibration of Voltage/Current Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+			return
+		}
+		if *c == (VoltageCurrentCalibration{vm, vd, cm, cd}) {
+			return
+		}
+		log.Infof("calibrating Voltage/Current Bricklet 2.0 (uid=%s): voltage %d/%d, current %d/%d",
+			dev.UID, c.VoltageMultiplier, c.VoltageDivisor, c.CurrentMultiplier, c.CurrentDivisor)
+		if err := d.SetCalibration(c.VoltageMultiplier, c.VoltageDivisor, c.CurrentMultiplier, c.CurrentDivisor); err != nil {
+			log.Errorf("failed to set calibration of Voltage/Current Bricklet 2.0 (uid=%s): %s", dev.UID, err)
+		}
+	}
+}
@@ -3,21 +3,30 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	flag "github.com/spf13/pflag"
+	"github.com/vetinari/brickd_exporter/collector"
 	"github.com/vetinari/brickd_exporter/mqtt"
 	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
 const (
 	defaultListenAddress = ":9639"
 	defaultMetricsPath   = "/metrics"
+	defaultBrickdAddress = "localhost:4223"
+
+	defaultCallbackPeriod = 10 * time.Second
 )
 
 type LocalConfig struct {
 	Listen    ListenConfig    `yaml:"listen"`
-	Brickd    BrickdConfig    `yaml:"brickd"`
+	Brickd    BrickdConfigs   `yaml:"brickd"`
 	Collector CollectorConfig `yaml:"collector"`
 	MQTT      *mqtt.MQTT      `yaml:"mqtt"`
 }
@@ -28,37 +37,191 @@ type ListenConfig struct {
 }
 
 type BrickdConfig struct {
-	Address  string `yaml:"address"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
+	Address     string            `yaml:"address"`
+	User        string            `yaml:"user"`
+	Password    string            `yaml:"password"`
+	Labels      map[string]string `yaml:"labels"`
+	IgnoredUIDs []string          `yaml:"ignored_uids"`
+}
+
+// BrickdConfigs is the list of brickd daemons to scrape. For backwards compatibility
+// a single daemon may still be configured as a map instead of a list.
+type BrickdConfigs []BrickdConfig
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface
+func (c *BrickdConfigs) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// the kind of the node decides the form, so the errors of the used form are returned
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	if _, ok := raw.([]interface{}); ok {
+		var list []BrickdConfig
+		if err := unmarshal(&list); err != nil {
+			return err
+		}
+		*c = list
+		return nil
+	}
+
+	var single BrickdConfig
+	if err := unmarshal(&single); err != nil {
+		return err
+	}
+	*c = BrickdConfigs{single}
+	return nil
 }
 
 type CollectorConfig struct {
-	LogLevel       string                                  `yaml:"log_level"`
-	CallbackPeriod time.Duration                           `yaml:"callback_period"`
-	IgnoredUIDs    []string                                `yaml:"ignored_uids"`
-	Labels         map[string]string                       `yaml:"labels"`
-	SensorLabels   map[string]map[string]map[string]string `yaml:"sensor_labels"`
-	LEDStatus      string                                  `yaml:"led_status"`
-	Expire         time.Duration                           `yaml:"expire_period"`
+	LogLevel        string                                  `yaml:"log_level"`
+	CallbackPeriod  time.Duration                           `yaml:"callback_period"`
+	IgnoredUIDs     []string                                `yaml:"ignored_uids"`
+	Labels          map[string]string                       `yaml:"labels"`
+	SensorLabels    map[string]map[string]map[string]string `yaml:"sensor_labels"`
+	LEDStatus       string                                  `yaml:"led_status"`
+	DeviceLEDStatus map[string]string                       `yaml:"device_led_status"`
+	Expire          time.Duration                           `yaml:"expire_period"`
+
+	ReceivedTimestamps bool `yaml:"received_timestamps"`
+	ValueTimestamps    bool `yaml:"value_timestamps"`
+
+	OutdoorWeatherMaxAge time.Duration `yaml:"outdoor_weather_max_age"`
+
+	SoundPressureLevel collector.SoundPressureLevelSettings            `yaml:"sound_pressure_level"`
+	Tanks              map[string]collector.TankSettings               `yaml:"tanks"`
+	Counters           map[string]map[string]collector.CounterSettings `yaml:"counters"`
+	VoltageCurrent     map[string]collector.VoltageCurrentSettings     `yaml:"voltage_current"`
+	LoadCells          map[string]collector.LoadCellSettings           `yaml:"load_cells"`
+	Vibration          map[string]collector.VibrationSettings          `yaml:"vibration"`
 }
 
+var (
+	configFile      = flag.String("config.file", "", "Path to configuration file.")
+	enableLifecycle = flag.Bool("web.enable-lifecycle", false, "Enable reloading the configuration via HTTP request.")
+	enableAdminAPI  = flag.Bool("web.enable-admin-api", false, "Enable taring and calibrating load cells via HTTP request.")
+)
+
+// configFileMu serializes the writes to the config file, see saveTare
+var configFileMu sync.Mutex
+
 func parseConfig() (*LocalConfig, error) {
-	var configFile = flag.String("config.file", "", "Path to configuration file.")
 	flag.Parse()
+	return loadConfig(*configFile)
+}
 
-	if *configFile == "" {
+// loadConfig reads and validates the config file, the default config is returned when
+// no file is given
+func loadConfig(configFile string) (*LocalConfig, error) {
+	if configFile == "" {
 		return defaultConfig()
 	}
 
-	file, err := os.Open(*configFile)
+	file, err := os.Open(configFile)
 	if err != nil {
 		return nil, fmt.Errorf("can not open config file: %s", err)
 	}
+	defer file.Close()
 
 	config := &LocalConfig{}
 	if err := yaml.NewDecoder(file).Decode(config); err != nil {
-		return nil, fmt.Errorf("error decoding config file %q: %s", *configFile, err)
+		return nil, fmt.Errorf("error decoding config file %q: %s", configFile, err)
+	}
+
+	if len(config.Brickd) == 0 {
+		config.Brickd = BrickdConfigs{{Address: defaultBrickdAddress}}
+	}
+	if config.Collector.CallbackPeriod == 0 {
+		config.Collector.CallbackPeriod = defaultCallbackPeriod
+	}
+	if config.MQTT == nil {
+		config.MQTT = &mqtt.MQTT{}
+	}
+	if err := collector.ValidLEDStatus(config.Collector.LEDStatus); err != nil {
+		return nil, fmt.Errorf("error in config file %q: led_status: %s", configFile, err)
+	}
+	for uid, status := range config.Collector.DeviceLEDStatus {
+		if err := collector.ValidLEDStatus(status); err != nil {
+			return nil, fmt.Errorf("error in config file %q: device_led_status of %s: %s", configFile, uid, err)
+		}
+	}
+
+	if config.Collector.OutdoorWeatherMaxAge > collector.OutdoorWeatherMaxAgeLimit {
+		return nil, fmt.Errorf("error in config file %q: outdoor_weather_max_age must not be longer than %s",
+			configFile, collector.OutdoorWeatherMaxAgeLimit)
+	}
+
+	if err := config.Collector.SoundPressureLevel.Validate(); err != nil {
+		return nil, fmt.Errorf("error in config file %q: sound_pressure_level: %s", configFile, err)
+	}
+
+	for uid, tank := range config.Collector.Tanks {
+		if err := tank.Validate(); err != nil {
+			return nil, fmt.Errorf("error in config file %q: tank of %s: %s", configFile, uid, err)
+		}
+	}
+
+	for uid, channels := range config.Collector.Counters {
+		for ch, counter := range channels {
+			if n, err := strconv.Atoi(ch); err != nil || n < 0 || n > 3 {
+				return nil, fmt.Errorf("error in config file %q: counters of %s: invalid channel %q", configFile, uid, ch)
+			}
+			if err := counter.Validate(); err != nil {
+				return nil, fmt.Errorf("error in config file %q: counter %s of %s: %s", configFile, ch, uid, err)
+			}
+		}
+	}
+
+	for uid, vc := range config.Collector.VoltageCurrent {
+		if err := vc.Validate(); err != nil {
+			return nil, fmt.Errorf("error in config file %q: voltage_current of %s: %s", configFile, uid, err)
+		}
+	}
+
+	for uid, lc := range config.Collector.LoadCells {
+		if err := lc.Validate(); err != nil {
+			return nil, fmt.Errorf("error in config file %q: load_cells of %s: %s", configFile, uid, err)
+		}
+	}
+
+	for uid, v := range config.Collector.Vibration {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("error in config file %q: vibration of %s: %s", configFile, uid, err)
+		}
+	}
+
+	// the probes of the One Wire Bricklet may be labeled by their ROM ID
+	for uid, sensors := range config.Collector.SensorLabels {
+		for key, labels := range sensors {
+			if !strings.Contains(key, "-") {
+				continue
+			}
+			id, err := collector.OneWireSensorID(key)
+			if err != nil {
+				return nil, fmt.Errorf("error in config file %q: sensor_labels of %s: %s", configFile, uid, err)
+			}
+			if _, ok := sensors[id]; ok {
+				return nil, fmt.Errorf("error in config file %q: sensor_labels of %s: probe %s configured by sensor id and ROM ID",
+					configFile, uid, key)
+			}
+			delete(sensors, key)
+			sensors[id] = labels
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, bd := range config.Brickd {
+		if bd.Address == "" {
+			return nil, fmt.Errorf("error in config file %q: brickd without address", configFile)
+		}
+		if seen[bd.Address] {
+			return nil, fmt.Errorf("error in config file %q: brickd %s configured more than once", configFile, bd.Address)
+		}
+		seen[bd.Address] = true
+	}
+
+	// the metrics are described with the label names and counter metrics of all brickds
+	if _, err := collector.NewMultiCollectorFor(config.settings()...); err != nil {
+		return nil, fmt.Errorf("error in config file %q: %s", configFile, err)
 	}
 
 	return config, nil
@@ -66,8 +229,10 @@ func parseConfig() (*LocalConfig, error) {
 
 func defaultConfig() (*LocalConfig, error) {
 	return &LocalConfig{
-		Brickd: BrickdConfig{
-			Address: "localhost:4223",
+		Brickd: BrickdConfigs{
+			{
+				Address: defaultBrickdAddress,
+			},
 		},
 		Listen: ListenConfig{
 			Address:     defaultListenAddress,
@@ -75,7 +240,7 @@ func defaultConfig() (*LocalConfig, error) {
 		},
 		Collector: CollectorConfig{
 			LogLevel:       "info",
-			CallbackPeriod: 10 * time.Second,
+			CallbackPeriod: defaultCallbackPeriod,
 			Expire:         0,
 			LEDStatus:      "on",
 		},
@@ -90,3 +255,201 @@ func defaultConfig() (*LocalConfig, error) {
 		},
 	}, nil
 }
+
+// settings returns the collector settings of each brickd
+func (config *LocalConfig) settings() []collector.Settings {
+	var settings []collector.Settings
+	for _, bd := range config.Brickd {
+		settings = append(settings, bd.settings(config.Collector))
+	}
+	return settings
+}
+
+// labels returns the collector wide labels merged with the labels of this brickd,
+// the latter take precedence
+func (bd BrickdConfig) labels(global map[string]string) map[string]string {
+	labels := make(map[string]string, len(global)+len(bd.Labels))
+	for k, v := range global {
+		labels[k] = v
+	}
+	for k, v := range bd.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// settings returns the settings of the collector for this brickd which can be changed
+// without reconnecting
+func (bd BrickdConfig) settings(c CollectorConfig) collector.Settings {
+	return collector.Settings{
+		IgnoredUIDs:        bd.ignoredUIDs(c.IgnoredUIDs),
+		Labels:             bd.labels(c.Labels),
+		SensorLabels:       c.SensorLabels,
+		ExpirePeriod:       c.Expire,
+		LEDStatus:          c.LEDStatus,
+		DeviceLEDStatus:    c.DeviceLEDStatus,
+		ReceivedTimestamps: c.ReceivedTimestamps,
+		ValueTimestamps:    c.ValueTimestamps,
+
+		OutdoorWeatherMaxAge: c.OutdoorWeatherMaxAge,
+
+		SoundPressureLevel: c.SoundPressureLevel,
+		Tanks:              c.Tanks,
+		Counters:           c.Counters,
+		VoltageCurrent:     c.VoltageCurrent,
+		LoadCells:          c.LoadCells,
+		Vibration:          c.Vibration,
+	}
+}
+
+// ignoredUIDs returns the collector wide ignored UIDs plus the ones of this brickd
+func (bd BrickdConfig) ignoredUIDs(global []string) []string {
+	uids := make([]string, 0, len(global)+len(bd.IgnoredUIDs))
+	uids = append(uids, global...)
+	return append(uids, bd.IgnoredUIDs...)
+}
+
+// saveTare sets collector.load_cells.<uid>.tare in the config file. Only the line of the tare
+// is changed, missing keys are inserted with the indentation of the file, so the rest of the
+// file is kept as it is. The file is replaced atomically, it is never written partially.
+func saveTare(configFile, uid string, tare float64) error {
+	configFileMu.Lock()
+	defer configFileMu.Unlock()
+
+	path, err := filepath.EvalSymlinks(configFile) // replace the file, not the link
+	if err != nil {
+		return fmt.Errorf("can not read config file: %s", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("can not read config file: %s", err)
+	}
+	out, err := setYAMLValue(data, []string{"collector", "load_cells", uid, "tare"}, strconv.FormatFloat(tare, 'f', -1, 64))
+	if err != nil {
+		return fmt.Errorf("error in config file %q: %s", configFile, err)
+	}
+	if err := writeFileAtomic(path, out); err != nil {
+		return fmt.Errorf("can not write config file: %s", err)
+	}
+	return nil
+}
+
+// defaultYAMLIndent is the indentation of inserted keys when the file has no nested keys to
+// take it from
+const defaultYAMLIndent = 4
+
+// setYAMLValue returns the yaml document data with the plain scalar at the path of keys set
+// to value. Only the text of the value is replaced, missing keys are inserted as block
+// mappings before the first key of the deepest existing mapping, with its indentation.
+func setYAMLValue(data []byte, keys []string, value string) ([]byte, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(doc.Content) == 0 { // empty file
+		return insertYAMLKeys(lines, len(lines), 0, defaultYAMLIndent, keys, value), nil
+	}
+
+	step := defaultYAMLIndent
+	node := doc.Content[0]
+	var parent *yamlv3.Node // key of node
+	for i, key := range keys {
+		if node.Kind == yamlv3.ScalarNode && node.Tag == "!!null" && node.Value == "" && parent != nil {
+			// a key without value, e.g. "load_cells:"
+			return insertYAMLKeys(lines, parent.Line, parent.Column-1+step, step, keys[i:], value), nil
+		}
+		if node.Kind != yamlv3.MappingNode || node.Style&yamlv3.FlowStyle != 0 || len(node.Content) == 0 {
+			return nil, fmt.Errorf("can not set %s: no block map at line %d", strings.Join(keys, "."), node.Line)
+		}
+		first := node.Content[0]
+		if parent != nil {
+			step = first.Column - parent.Column
+		}
+		var next *yamlv3.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == key {
+				parent, next = node.Content[j], node.Content[j+1]
+				break
+			}
+		}
+		if next == nil {
+			return insertYAMLKeys(lines, first.Line-1, first.Column-1, step, keys[i:], value), nil
+		}
+		node = next
+	}
+
+	if node.Kind != yamlv3.ScalarNode || node.Style != 0 {
+		return nil, fmt.Errorf("can not set %s: no plain value at line %d", strings.Join(keys, "."), node.Line)
+	}
+	if node.Tag == "!!null" && node.Value == "" { // "tare:" without value
+		line := lines[parent.Line-1]
+		end := yamlOffset(line, parent.Column) + len(parent.Value) + 1 // after the colon
+		lines[parent.Line-1] = line[:end] + " " + value + line[end:]
+		return []byte(strings.Join(lines, "")), nil
+	}
+	line := lines[node.Line-1]
+	start := yamlOffset(line, node.Column)
+	if !strings.HasPrefix(line[start:], node.Value) {
+		return nil, fmt.Errorf("can not set %s: no plain value at line %d", strings.Join(keys, "."), node.Line)
+	}
+	lines[node.Line-1] = line[:start] + value + line[start+len(node.Value):]
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// yamlOffset returns the byte offset of the 1-based column of a yaml node in line
+func yamlOffset(line string, column int) int {
+	runes := []rune(line)
+	if column-1 > len(runes) {
+		return len(line)
+	}
+	return len(string(runes[:column-1]))
+}
+
+// insertYAMLKeys inserts the keys as nested block mappings with the value of the last key
+// before lines[at], the first key is indented by indent spaces, each further key by step more
+func insertYAMLKeys(lines []string, at, indent, step int, keys []string, value string) []byte {
+	if at > 0 && lines[at-1] != "" && !strings.HasSuffix(lines[at-1], "\n") {
+		lines[at-1] += "\n"
+	}
+	var insert []string
+	for i, key := range keys {
+		line := strings.Repeat(" ", indent+i*step) + key + ":"
+		if i == len(keys)-1 {
+			line += " " + value
+		}
+		insert = append(insert, line+"\n")
+	}
+	out := append(append(append([]string{}, lines[:at]...), insert...), lines[at:]...)
+	return []byte(strings.Join(out, ""))
+}
+
+// writeFileAtomic writes data to a temporary file in the directory of path and renames it
+// to path, keeping the mode of the file
+func writeFileAtomic(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails after the rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,450 @@
+// Package fakebrickd is an in-process brickd speaking the Tinkerforge TCP/IP protocol. It
+// enumerates scripted devices and fires callbacks with chosen values, so the collector can be
+// tested without hardware.
+//
+// Requests without a handler are answered with an empty response, i.e. setters succeed and
+// getters fail with an unexpected size error in the bindings. The identity (function 255),
+// enumerate (254) and authentication requests are answered by the server itself.
+package fakebrickd
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	headerSize = 8
+
+	functionAuthNonce     = 1
+	functionAuthenticate  = 2
+	functionDisconnect    = 128
+	functionEnumerate     = 254
+	functionGetIdentity   = 255
+	functionCallbackEnum  = 253
+	authUID               = 1
+	errorInvalidParameter = 1
+)
+
+// Enumeration types sent in the enumerate callback
+const (
+	EnumerationTypeAvailable    uint8 = 0
+	EnumerationTypeConnected    uint8 = 1
+	EnumerationTypeDisconnected uint8 = 2
+)
+
+// Device is a brick or bricklet connected to the Server
+type Device struct {
+	UID              string
+	ConnectedUID     string
+	Position         byte // '0' - '8' for bricks, 'a' - 'h' for bricklets
+	HardwareVersion  [3]uint8
+	FirmwareVersion  [3]uint8
+	DeviceIdentifier uint16
+}
+
+// Request is a request received from a client
+type Request struct {
+	UID        string
+	FunctionID uint8
+	Payload    []byte
+}
+
+// HandlerFunc returns the response payload for a request payload
+type HandlerFunc func(payload []byte) []byte
+
+type handlerKey struct {
+	uid        uint32
+	functionID uint8
+}
+
+// Server is a fake brickd listening on a local port
+type Server struct {
+	// Secret enables authentication, all requests except the authentication are ignored
+	// until a client authenticated
+	Secret string
+
+	mu       sync.Mutex
+	ln       net.Listener
+	devices  []Device
+	handlers map[handlerKey]HandlerFunc
+	conns    map[*conn]struct{}
+	requests []Request
+	notify   chan struct{}
+}
+
+type conn struct {
+	sync.Mutex
+	net.Conn
+	nonce         []byte
+	authenticated bool
+}
+
+// New starts a Server listening on a random port of 127.0.0.1
+func New() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %s", err)
+	}
+	s := &Server{
+		ln:       ln,
+		handlers: make(map[handlerKey]HandlerFunc),
+		conns:    make(map[*conn]struct{}),
+		notify:   make(chan struct{}),
+	}
+	go s.serve()
+	return s, nil
+}
+
+// Addr is the address clients connect to
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close stops listening and closes all client connections
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.DropConnections()
+	return err
+}
+
+// DropConnections closes all client connections, the server keeps listening
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+		delete(s.conns, c)
+	}
+}
+
+// Connections returns the number of connected clients
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// AddDevice adds a device, connected clients get an enumerate callback with
+// EnumerationTypeConnected
+func (s *Server) AddDevice(dev Device) error {
+	if _, err := UIDToU32(dev.UID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.devices = append(s.devices, dev)
+	s.mu.Unlock()
+	return s.broadcast(enumeratePacket(dev, EnumerationTypeConnected))
+}
+
+// RemoveDevice removes a device, connected clients get an enumerate callback with
+// EnumerationTypeDisconnected
+func (s *Server) RemoveDevice(uid string) error {
+	s.mu.Lock()
+	var removed *Device
+	for i, dev := range s.devices {
+		if dev.UID == uid {
+			removed = &dev
+			s.devices = append(s.devices[:i], s.devices[i+1:]...)
+			break
+		}
+	}
+	s.mu.Unlock()
+	if removed == nil {
+		return fmt.Errorf("no device with uid %s", uid)
+	}
+	return s.broadcast(enumeratePacket(*removed, EnumerationTypeDisconnected))
+}
+
+// Handle sets the handler for a function of a device, it replaces the default empty response
+func (s *Server) Handle(uid string, functionID uint8, fn HandlerFunc) error {
+	id, err := UIDToU32(uid)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[handlerKey{id, functionID}] = fn
+	return nil
+}
+
+// Respond sets a handler for a function of a device which always responds with the given
+// values, encoded as with Encode
+func (s *Server) Respond(uid string, functionID uint8, values ...interface{}) error {
+	payload := Encode(values...)
+	return s.Handle(uid, functionID, func([]byte) []byte { return payload })
+}
+
+// Callback sends a callback of a device with the values encoded as with Encode to all
+// connected clients
+func (s *Server) Callback(uid string, functionID uint8, values ...interface{}) error {
+	id, err := UIDToU32(uid)
+	if err != nil {
+		return err
+	}
+	return s.broadcast(packet(id, functionID, 0, Encode(values...)))
+}
+
+// Requests returns the requests received for a function of a device, except for identity requests
+func (s *Server) Requests(uid string, functionID uint8) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var reqs []Request
+	for _, r := range s.requests {
+		if r.UID == uid && r.FunctionID == functionID {
+			reqs = append(reqs, r)
+		}
+	}
+	return reqs
+}
+
+// WaitForRequest waits until a request for a function of a device has been received and
+// returns the latest one
+func (s *Server) WaitForRequest(uid string, functionID uint8, timeout time.Duration) (Request, error) {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		notify := s.notify
+		s.mu.Unlock()
+		if reqs := s.Requests(uid, functionID); len(reqs) > 0 {
+			return reqs[len(reqs)-1], nil
+		}
+		select {
+		case <-notify:
+		case <-deadline:
+			return Request{}, fmt.Errorf("no request for function %d of %s within %s", functionID, uid, timeout)
+		}
+	}
+}
+
+func (s *Server) serve() {
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := &conn{Conn: nc}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+		go s.handle(c)
+	}
+}
+
+func (s *Server) handle(c *conn) {
+	defer func() {
+		c.Close()
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(c, header); err != nil {
+			return
+		}
+		length := int(header[4])
+		if length < headerSize {
+			return
+		}
+		payload := make([]byte, length-headerSize)
+		if _, err := io.ReadFull(c, payload); err != nil {
+			return
+		}
+		uid := binary.LittleEndian.Uint32(header[0:4])
+		functionID := header[5]
+		seq := header[6] >> 4
+		responseExpected := header[6]&0x08 != 0
+
+		if uid == authUID && (functionID == functionAuthNonce || functionID == functionAuthenticate) {
+			s.authenticate(c, functionID, seq, payload)
+			continue
+		}
+		if s.Secret != "" && !c.authenticated {
+			continue // brickd ignores unauthenticated requests
+		}
+
+		switch {
+		case functionID == functionDisconnect:
+			continue
+		case uid == 0 && functionID == functionEnumerate:
+			s.mu.Lock()
+			devices := append([]Device{}, s.devices...)
+			s.mu.Unlock()
+			for _, dev := range devices {
+				c.write(enumeratePacket(dev, EnumerationTypeAvailable))
+			}
+			continue
+		}
+
+		s.mu.Lock()
+		dev, known := s.device(uid)
+		fn := s.handlers[handlerKey{uid, functionID}]
+		if functionID != functionGetIdentity {
+			s.requests = append(s.requests, Request{UID: U32ToUID(uid), FunctionID: functionID, Payload: payload})
+			close(s.notify)
+			s.notify = make(chan struct{})
+		}
+		s.mu.Unlock()
+
+		if !known {
+			continue // a request for a device which does not exist times out
+		}
+		if !responseExpected {
+			continue
+		}
+		var resp []byte
+		switch {
+		case fn != nil:
+			resp = fn(payload)
+		case functionID == functionGetIdentity:
+			resp = identityPayload(dev)
+		}
+		c.write(packet(uid, functionID, seq, resp))
+	}
+}
+
+func (s *Server) authenticate(c *conn, functionID, seq uint8, payload []byte) {
+	switch functionID {
+	case functionAuthNonce:
+		c.nonce = []byte{0x17, 0x42, 0x23, 0x05}
+		c.write(packet(authUID, functionID, seq, c.nonce))
+	case functionAuthenticate:
+		if len(payload) != 24 || c.nonce == nil {
+			c.write(errorPacket(authUID, functionID, seq, errorInvalidParameter))
+			return
+		}
+		clientNonce, digest := payload[:4], payload[4:]
+		mac := hmac.New(sha1.New, []byte(s.Secret))
+		mac.Write(append(append([]byte{}, c.nonce...), clientNonce...))
+		if !hmac.Equal(mac.Sum(nil), digest) {
+			c.write(errorPacket(authUID, functionID, seq, errorInvalidParameter))
+			return
+		}
+		c.authenticated = true
+		c.write(packet(authUID, functionID, seq, nil))
+	}
+}
+
+// device returns the device with the numeric uid, must be called with s.mu locked
+func (s *Server) device(uid uint32) (Device, bool) {
+	for _, dev := range s.devices {
+		if id, _ := UIDToU32(dev.UID); id == uid {
+			return dev, true
+		}
+	}
+	return Device{}, false
+}
+
+func (s *Server) broadcast(p []byte) error {
+	s.mu.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		if s.Secret != "" && !c.authenticated {
+			continue
+		}
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	var err error
+	for _, c := range conns {
+		if e := c.write(p); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (c *conn) write(p []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	_, err := c.Write(p)
+	return err
+}
+
+func packet(uid uint32, functionID, seq uint8, payload []byte) []byte {
+	p := make([]byte, headerSize, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(p[0:4], uid)
+	p[4] = uint8(headerSize + len(payload))
+	p[5] = functionID
+	p[6] = seq << 4
+	return append(p, payload...)
+}
+
+func errorPacket(uid uint32, functionID, seq, code uint8) []byte {
+	p := packet(uid, functionID, seq, nil)
+	p[7] = code << 6
+	return p
+}
+
+// identityPayload is the response of the get identity function
+func identityPayload(dev Device) []byte {
+	return Encode(uidBytes(dev.UID), uidBytes(dev.ConnectedUID), dev.Position,
+		dev.HardwareVersion, dev.FirmwareVersion, dev.DeviceIdentifier)
+}
+
+func enumeratePacket(dev Device, enumerationType uint8) []byte {
+	return packet(0, functionCallbackEnum, 0, append(identityPayload(dev), enumerationType))
+}
+
+func uidBytes(uid string) [8]byte {
+	var b [8]byte
+	copy(b[:], uid)
+	return b
+}
+
+// Encode encodes the values little endian as the Tinkerforge protocol does, the values
+// must be fixed size, e.g. uint16, bool or [3]uint8
+func Encode(values ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			panic(fmt.Sprintf("failed to encode %#v: %s", v, err))
+		}
+	}
+	return buf.Bytes()
+}
+
+const alphabet = "123456789abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// UIDToU32 decodes a base58 UID, only UIDs fitting into 32 bit are supported
+func UIDToU32(uid string) (uint32, error) {
+	if uid == "" {
+		return 0, fmt.Errorf("UID is empty")
+	}
+	var v uint64
+	for _, r := range uid {
+		i := strings.IndexRune(alphabet, r)
+		if i == -1 {
+			return 0, fmt.Errorf("UID %s contains an invalid character", uid)
+		}
+		v = v*uint64(len(alphabet)) + uint64(i)
+		if v > 0xFFFFFFFF {
+			return 0, fmt.Errorf("UID %s does not fit into 32 bit", uid)
+		}
+	}
+	if v == 0 {
+		return 0, fmt.Errorf("UID %s is mapped to zero", uid)
+	}
+	return uint32(v), nil
+}
+
+// U32ToUID encodes a numeric UID as base58
+func U32ToUID(v uint32) string {
+	if v == 0 {
+		return "1"
+	}
+	var s []byte
+	for v > 0 {
+		s = append([]byte{alphabet[v%uint32(len(alphabet))]}, s...)
+		v /= uint32(len(alphabet))
+	}
+	return string(s)
+}
@@ -4,8 +4,6 @@ import (
 	"net/http"
 	// _ "net/http/pprof"
 
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"github.com/vetinari/brickd_exporter/collector"
 )
@@ -16,32 +14,20 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error loading configuration: %s", err)
 	}
-	if config.Collector.LogLevel == "" {
-		config.Collector.LogLevel = "info"
-	}
-	lvl, err := log.ParseLevel(config.Collector.LogLevel)
+	setLogLevel(config.Collector.LogLevel)
+
+	e, err := newExporter(config)
 	if err != nil {
-		log.Errorf("failed to parse `log_level` %s: %s", config.Collector.LogLevel, err)
-		lvl = log.InfoLevel
+		log.Fatalf("Error starting exporter: %s", err)
 	}
-	log.SetLevel(lvl)
-
-	c := collector.NewCollector(
-		config.Brickd.Address,
-		config.Brickd.Password,
-		config.Collector.CallbackPeriod,
-		config.Collector.IgnoredUIDs,
-		config.Collector.Labels,
-		config.Collector.SensorLabels,
-		config.Collector.Expire,
-		config.MQTT,
-	)
-
-	prometheus.MustRegister(c)
+	go e.reloadOnSIGHUP()
 
 	listenAddress := config.Listen.Address
 
-	http.Handle(config.Listen.MetricsPath, promhttp.Handler())
+	http.HandleFunc(config.Listen.MetricsPath, e.MetricsHandler)
+	http.HandleFunc("/devices", e.DevicesHandler)
+	http.HandleFunc("/api/devices/", e.APIHandler)
+	http.HandleFunc("/-/reload", e.ReloadHandler)
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, config.Listen.MetricsPath, http.StatusFound)
 	})
@@ -52,3 +38,16 @@ func main() {
 		log.Fatalf("Cannot start WL exporter: %s", err)
 	}
 }
+
+// setLogLevel sets the log level from the config, defaults to info
+func setLogLevel(level string) {
+	if level == "" {
+		level = "info"
+	}
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		log.Errorf("failed to parse `log_level` %s: %s", level, err)
+		lvl = log.InfoLevel
+	}
+	log.SetLevel(lvl)
+}
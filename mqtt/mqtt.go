@@ -3,6 +3,7 @@ package mqtt
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
@@ -69,6 +70,9 @@ var connectLostHandler mqtt.ConnectionLostHandler = func(client mqtt.Client, err
 
 type Client struct {
 	c mqtt.Client
+
+	mu   sync.Mutex
+	subs map[string]func(payload []byte) // by topic
 }
 
 func NewClient(broker *Broker) (*Client, error) {
@@ -81,14 +85,18 @@ func NewClient(broker *Broker) (*Client, error) {
 	opts.SetUsername(broker.Username)
 	opts.SetPassword(broker.Password)
 
+	c := &Client{subs: make(map[string]func([]byte))}
 	opts.SetDefaultPublishHandler(messagePubHandler)
-	opts.OnConnect = connectHandler
+	opts.OnConnect = func(client mqtt.Client) {
+		connectHandler(client)
+		c.resubscribe(client)
+	}
 	opts.OnConnectionLost = connectLostHandler
-	client := mqtt.NewClient(opts)
-	if token := client.Connect(); token.Wait() && token.Error() != nil {
+	c.c = mqtt.NewClient(opts)
+	if token := c.c.Connect(); token.Wait() && token.Error() != nil {
 		return nil, fmt.Errorf("failed to connect to broker: %w", token.Error())
 	}
-	return &Client{c: client}, nil
+	return c, nil
 }
 
 func (c *Client) Publish(topic string, data []byte) {
@@ -96,6 +104,58 @@ func (c *Client) Publish(topic string, data []byte) {
 	token.Wait()
 }
 
+// Subscribe calls fn with the payload of each message received on the topic until
+// Unsubscribe is called. The subscription is renewed after reconnecting to the broker.
+func (c *Client) Subscribe(topic string, fn func(payload []byte)) error {
+	c.mu.Lock()
+	c.subs[topic] = fn
+	c.mu.Unlock()
+	token := c.c.Subscribe(topic, 1, c.handle)
+	token.Wait()
+	return token.Error()
+}
+
+// Unsubscribe stops calling the func given to Subscribe for the topic
+func (c *Client) Unsubscribe(topic string) error {
+	c.mu.Lock()
+	delete(c.subs, topic)
+	c.mu.Unlock()
+	token := c.c.Unsubscribe(topic)
+	token.Wait()
+	return token.Error()
+}
+
+// handle passes a received message to the func of its topic, messages received after
+// Unsubscribe are dropped
+func (c *Client) handle(_ mqtt.Client, msg mqtt.Message) {
+	c.mu.Lock()
+	fn, ok := c.subs[msg.Topic()]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	log.WithFields(log.Fields{
+		"type":    "mqtt",
+		"topic":   msg.Topic(),
+		"payload": string(msg.Payload()),
+	}).Debug("received command")
+	fn(msg.Payload())
+}
+
+// resubscribe renews all subscriptions, the broker forgets them when the connection is lost
+func (c *Client) resubscribe(client mqtt.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for topic := range c.subs {
+		client.Subscribe(topic, 1, c.handle)
+	}
+}
+
 func (c *Client) Client() mqtt.Client {
 	return c.c
 }
+
+// Disconnect closes the connection to the broker, waiting up to 250ms for pending messages
+func (c *Client) Disconnect() {
+	c.c.Disconnect(250)
+}
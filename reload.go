@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"github.com/vetinari/brickd_exporter/collector"
+	"github.com/vetinari/brickd_exporter/mqtt"
+)
+
+// exporter holds the collectors of the running config, which is replaced by reload
+type exporter struct {
+	sync.RWMutex
+	config     *LocalConfig
+	collectors map[string]*collector.BrickdCollector // by brickd address
+	mc         *collector.MultiCollector
+	metrics    http.Handler
+}
+
+// newExporter starts the collectors for the config
+func newExporter(config *LocalConfig) (*exporter, error) {
+	mc, metrics, err := newMetrics(config)
+	if err != nil {
+		return nil, err
+	}
+	e := &exporter{
+		config:     config,
+		collectors: make(map[string]*collector.BrickdCollector),
+		mc:         mc,
+		metrics:    metrics,
+	}
+	for _, bd := range config.Brickd {
+		c := newCollector(bd, config)
+		e.collectors[bd.Address] = c
+		mc.Collectors = append(mc.Collectors, c)
+	}
+	return e, nil
+}
+
+// newMetrics creates the MultiCollector for the brickds of the config, without the
+// collectors, and a new registry for it. A registry can not be reused as the label names of
+// the metrics change with the config.
+func newMetrics(config *LocalConfig) (*collector.MultiCollector, http.Handler, error) {
+	mc, err := collector.NewMultiCollectorFor(config.settings()...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create collector: %s", err)
+	}
+	mc.AdminAPI = *enableAdminAPI
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if err := reg.Register(mc); err != nil {
+		return nil, nil, fmt.Errorf("failed to register collector: %s", err)
+	}
+	return mc, promhttp.InstrumentMetricHandler(reg, promhttp.HandlerFor(reg, promhttp.HandlerOpts{})), nil
+}
+
+func newCollector(bd BrickdConfig, config *LocalConfig) *collector.BrickdCollector {
+	c := collector.NewCollector(
+		bd.Address,
+		bd.Password,
+		config.Collector.CallbackPeriod,
+		bd.ignoredUIDs(config.Collector.IgnoredUIDs),
+		bd.labels(config.Collector.Labels),
+		config.Collector.SensorLabels,
+		config.Collector.Expire,
+		config.MQTT,
+	)
+	c.Reload(bd.settings(config.Collector))
+	if *configFile != "" {
+		c.SetTareSaver(func(uid string, tare float64) error {
+			return saveTare(*configFile, uid, tare)
+		})
+	}
+	return c
+}
+
+// reload reads the config file again and applies the changes. Labels, ignored UIDs, expiry,
+// LED status and the MQTT topic are changed without reconnecting, the MQTT client is only
+// restarted when the broker changed. A brickd is reconnected when its password, the callback
+// period or the HomeAssistant settings changed. On errors, e.g. when the new MQTT broker can
+// not be connected, the running config is kept.
+func (e *exporter) reload() error {
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	// everything which can fail is done before the running config is changed
+	mc, metrics, err := newMetrics(config)
+	if err != nil {
+		return err
+	}
+
+	reconnect, err := e.reloadMQTT(config.MQTT)
+	if err != nil {
+		return err
+	}
+
+	setLogLevel(config.Collector.LogLevel)
+	if config.Listen != e.config.Listen {
+		log.Warnf("changed listen settings are applied after a restart")
+		config.Listen = e.config.Listen
+	}
+
+	if config.Collector.CallbackPeriod != e.config.Collector.CallbackPeriod {
+		reconnect = true
+	}
+
+	old := make(map[string]BrickdConfig)
+	for _, bd := range e.config.Brickd {
+		old[bd.Address] = bd
+	}
+	running := make(map[string]*collector.BrickdCollector)
+	for _, bd := range config.Brickd {
+		c, ok := e.collectors[bd.Address]
+		switch {
+		case ok && !reconnect && old[bd.Address].Password == bd.Password:
+			c.SetMQTT(config.MQTT)
+			c.Reload(bd.settings(config.Collector))
+		case ok:
+			log.Infof("reconnecting to brickd %s", bd.Address)
+			c.Close()
+			c = newCollector(bd, config)
+		default:
+			log.Infof("adding brickd %s", bd.Address)
+			c = newCollector(bd, config)
+		}
+		running[bd.Address] = c
+		mc.Collectors = append(mc.Collectors, c)
+	}
+	for addr, c := range e.collectors {
+		if _, ok := running[addr]; !ok {
+			log.Infof("removing brickd %s", addr)
+			c.Close()
+		}
+	}
+
+	// the old MQTT client is only disconnected when no collector uses it anymore
+	if oldClient := e.config.MQTT.Client; oldClient != nil && oldClient != config.MQTT.Client {
+		log.Infof("disconnecting from MQTT broker")
+		oldClient.Disconnect()
+	}
+	e.config = config
+	e.collectors = running
+	e.mc = mc
+	e.metrics = metrics
+	log.Infof("configuration reloaded")
+	return nil
+}
+
+// reloadMQTT sets the MQTT client of mq, the running client is reused when the broker did
+// not change, otherwise a new client is connected. The running client is not disconnected,
+// so an error keeps the running config. reconnect is true when the collectors must reconnect
+// to publish the HomeAssistant configs again.
+func (e *exporter) reloadMQTT(mq *mqtt.MQTT) (reconnect bool, err error) {
+	old := e.config.MQTT
+	sameBroker := old.Enabled && mq.Enabled && old.Broker != nil && mq.Broker != nil && *old.Broker == *mq.Broker
+	if sameBroker {
+		mq.Client = old.Client
+	}
+	if mq.Enabled && mq.Client == nil {
+		mq.Client, err = mqtt.NewClient(mq.Broker)
+		if err != nil {
+			return false, fmt.Errorf("failed to create MQTT client: %s", err)
+		}
+	}
+	if !mq.HomeAssistant.Enabled && !old.HomeAssistant.Enabled {
+		return false, nil
+	}
+	return mq.HomeAssistant != old.HomeAssistant || mq.Enabled != old.Enabled || !sameBroker, nil
+}
+
+// MetricsHandler serves the metrics of the running config
+func (e *exporter) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	e.RLock()
+	h := e.metrics
+	e.RUnlock()
+	h.ServeHTTP(w, r)
+}
+
+// DevicesHandler serves the devices of the running config, see collector.MultiCollector.DevicesHandler
+func (e *exporter) DevicesHandler(w http.ResponseWriter, r *http.Request) {
+	e.RLock()
+	mc := e.mc
+	e.RUnlock()
+	mc.DevicesHandler(w, r)
+}
+
+// APIHandler serves the device API of the running config, see collector.MultiCollector.APIHandler
+func (e *exporter) APIHandler(w http.ResponseWriter, r *http.Request) {
+	e.RLock()
+	mc := e.mc
+	e.RUnlock()
+	mc.APIHandler(w, r)
+}
+
+// ReloadHandler reloads the config on POST /-/reload, it is only enabled with
+// --web.enable-lifecycle as the endpoint is not authenticated
+func (e *exporter) ReloadHandler(w http.ResponseWriter, r *http.Request) {
+	if !*enableLifecycle {
+		http.Error(w, "lifecycle API is not enabled", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := e.reload(); err != nil {
+		log.Errorf("failed to reload configuration: %s", err)
+		http.Error(w, fmt.Sprintf("failed to reload configuration: %s", err), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintln(w, "configuration reloaded")
+}
+
+// reloadOnSIGHUP reloads the config whenever the process receives a SIGHUP
+func (e *exporter) reloadOnSIGHUP() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		if err := e.reload(); err != nil {
+			log.Errorf("failed to reload configuration: %s", err)
+		}
+	}
+}